@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"techdocs/internal/config"
 	"techdocs/internal/handler"
@@ -9,6 +10,8 @@ import (
 	"techdocs/internal/service"
 	"techdocs/pkg/database"
 	"techdocs/pkg/logger"
+	"techdocs/pkg/notify"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -41,16 +44,78 @@ func main() {
 	userRepo := repository.NewUserRepository(db)
 	documentRepo := repository.NewDocumentRepository(db)
 	serviceRepo := repository.NewServiceRepository(db)
+	permissionRepo := repository.NewPermissionRepository(db)
+	teamRepo := repository.NewTeamRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	searchRepo := repository.NewSearchRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	reviewRepo := repository.NewReviewRepository(db)
+	trashRepo := repository.NewTrashRepository(db)
+	spaceRepo := repository.NewSpaceRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
 
 	// Initialize services
+	approvalRules := service.ApprovalRules{Default: cfg.Review.DefaultApprovals}
 	userService := service.NewUserService(userRepo, cfg.JWTSecret)
-	documentService := service.NewDocumentService(documentRepo)
-	serviceService := service.NewServiceService(serviceRepo)
+	notificationService := service.NewNotificationService(notificationRepo, subscriptionRepo, userRepo, documentRepo, roleRepo)
+	webhookService := service.NewWebhookService(webhookRepo)
+	documentService := service.NewDocumentService(documentRepo, userRepo, permissionRepo, teamRepo, reviewRepo, roleRepo, spaceRepo, categoryRepo, approvalRules, notificationService, webhookService)
+	serviceService := service.NewServiceService(serviceRepo, teamRepo, categoryRepo, webhookService)
+	teamService := service.NewTeamService(teamRepo, userRepo)
+	roleService := service.NewRoleService(roleRepo, userRepo)
+	searchService := service.NewSearchService(searchRepo)
+	subscriptionService := service.NewSubscriptionService(subscriptionRepo, documentRepo, serviceRepo, tagRepo)
+	commentService := service.NewCommentService(commentRepo, documentRepo, permissionRepo, teamRepo, notificationService, webhookService)
+	spaceService := service.NewSpaceService(spaceRepo, documentRepo, teamRepo)
+	categoryService := service.NewCategoryService(categoryRepo)
+	tagService := service.NewTagService(tagRepo, documentRepo)
+	trashService := service.NewTrashService(trashRepo, permissionRepo, teamRepo, webhookService, time.Duration(cfg.TrashRetentionDays)*24*time.Hour)
+
+	// Start webhook deliveries
+	go webhookService.Run(context.Background())
+
+	// Start purging the trash
+	go trashService.Run(context.Background())
+
+	// Start e-mail delivery of notifications
+	if cfg.SMTP.Host != "" {
+		notifier := notify.Retry{
+			Notifier: notify.NewSMTPNotifier(notify.SMTPConfig{
+				Host:     cfg.SMTP.Host,
+				Port:     cfg.SMTP.Port,
+				Username: cfg.SMTP.Username,
+				Password: cfg.SMTP.Password,
+				From:     cfg.SMTP.From,
+			}),
+			Attempts: 3,
+			Backoff:  2 * time.Second,
+		}
+		deliveryService := service.NewDeliveryService(notificationRepo, notifier, cfg.AppURL, cfg.DigestHour)
+		go deliveryService.Run(context.Background())
+		logger.Info("E-mail delivery started via %s:%s", cfg.SMTP.Host, cfg.SMTP.Port)
+	} else {
+		logger.Info("E-mail delivery disabled, SMTP_HOST is not set")
+	}
 
 	// Initialize handlers
-	userHandler := handler.NewUserHandler(userService)
+	userHandler := handler.NewUserHandler(userService, roleService)
 	documentHandler := handler.NewDocumentHandler(documentService)
 	serviceHandler := handler.NewServiceHandler(serviceService)
+	teamHandler := handler.NewTeamHandler(teamService)
+	roleHandler := handler.NewRoleHandler(roleService)
+	searchHandler := handler.NewSearchHandler(searchService)
+	commentHandler := handler.NewCommentHandler(commentService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	trashHandler := handler.NewTrashHandler(trashService)
+	spaceHandler := handler.NewSpaceHandler(spaceService)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
+	tagHandler := handler.NewTagHandler(tagService)
 
 	// Initialize Gin router
 	router := gin.Default()
@@ -58,11 +123,15 @@ func main() {
 	// Add logger middleware
 	router.Use(middleware.LoggerMiddleware())
 
+	// Tag requests with an ID and answer errors with problem details
+	router.Use(middleware.RequestID(), middleware.Errors())
+
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-ID")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -75,10 +144,21 @@ func main() {
 
 	// API routes group
 	api := router.Group("/api")
-	api.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	api.Use(middleware.AuthMiddleware(cfg.JWTSecret), middleware.LoadPermissions(roleService))
 	{
 		documentHandler.RegisterRoutes(api)
 		serviceHandler.RegisterRoutes(api)
+		teamHandler.RegisterRoutes(api)
+		roleHandler.RegisterRoutes(api)
+		searchHandler.RegisterRoutes(api)
+		commentHandler.RegisterRoutes(api)
+		notificationHandler.RegisterRoutes(api)
+		subscriptionHandler.RegisterRoutes(api)
+		webhookHandler.RegisterRoutes(api)
+		trashHandler.RegisterRoutes(api)
+		spaceHandler.RegisterRoutes(api)
+		categoryHandler.RegisterRoutes(api)
+		tagHandler.RegisterRoutes(api)
 	}
 
 	// Health check
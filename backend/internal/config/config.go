@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -15,8 +16,30 @@ type Config struct {
 		Password string
 		Name     string
 	}
-	JWTSecret  string
-	ServerPort string
+	// SMTP configures e-mail delivery of notifications, which is off while
+	// Host is empty
+	SMTP struct {
+		Host     string
+		Port     string
+		Username string
+		Password string
+		From     string
+	}
+	// AppURL is the frontend address used for links in e-mails
+	AppURL string
+	// DigestHour is the hour of the day, in server time, at which daily
+	// digests are sent
+	DigestHour int
+	// Review sets how many approvals a document needs before it can be
+	// published when its category doesn't set its own
+	Review struct {
+		DefaultApprovals int
+	}
+	// TrashRetentionDays is how long deleted documents and services stay
+	// in the trash before they are purged, 0 keeps them forever
+	TrashRetentionDays int
+	JWTSecret          string
+	ServerPort         string
 }
 
 func LoadConfig() (*Config, error) {
@@ -36,6 +59,30 @@ func LoadConfig() (*Config, error) {
 	config.JWTSecret = getEnvOrDefault("JWT_SECRET", "your-secret-key")
 	config.ServerPort = getEnvOrDefault("SERVER_PORT", "8081")
 
+	// E-mail notifications
+	config.SMTP.Host = os.Getenv("SMTP_HOST")
+	config.SMTP.Port = getEnvOrDefault("SMTP_PORT", "587")
+	config.SMTP.Username = os.Getenv("SMTP_USERNAME")
+	config.SMTP.Password = os.Getenv("SMTP_PASSWORD")
+	config.SMTP.From = getEnvOrDefault("SMTP_FROM", "techdocs@localhost")
+	config.AppURL = getEnvOrDefault("APP_URL", "http://localhost:5173")
+	config.DigestHour, err = strconv.Atoi(getEnvOrDefault("DIGEST_HOUR", "8"))
+	if err != nil || config.DigestHour < 0 || config.DigestHour > 23 {
+		return nil, fmt.Errorf("DIGEST_HOUR must be an hour between 0 and 23")
+	}
+
+	// Document reviews
+	config.Review.DefaultApprovals, err = strconv.Atoi(getEnvOrDefault("REVIEW_DEFAULT_APPROVALS", "1"))
+	if err != nil || config.Review.DefaultApprovals < 1 {
+		return nil, fmt.Errorf("REVIEW_DEFAULT_APPROVALS must be a positive number")
+	}
+
+	// Trash
+	config.TrashRetentionDays, err = strconv.Atoi(getEnvOrDefault("TRASH_RETENTION_DAYS", "30"))
+	if err != nil || config.TrashRetentionDays < 0 {
+		return nil, fmt.Errorf("TRASH_RETENTION_DAYS must be a number of days, 0 to keep deleted items")
+	}
+
 	return config, nil
 }
 
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"techdocs/internal/middleware"
+	"techdocs/internal/model"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CategoryRequest is the body for creating or updating a category. The
+// slug is made from the name when empty and a position of 0 puts a new
+// category last. RequiredApprovals of 0 leaves documents in the category
+// at the default number of approvals.
+type CategoryRequest struct {
+	Name              string `json:"name" binding:"required,max=100"`
+	Slug              string `json:"slug" binding:"max=100"`
+	Description       string `json:"description" binding:"max=1000"`
+	Position          int    `json:"position" binding:"min=0"`
+	RequiredApprovals int    `json:"required_approvals" binding:"min=0"`
+}
+
+// toModel turns the request into the category it describes
+func (r *CategoryRequest) toModel() *model.Category {
+	return &model.Category{
+		Name:              r.Name,
+		Slug:              r.Slug,
+		Description:       r.Description,
+		Position:          r.Position,
+		RequiredApprovals: r.RequiredApprovals,
+	}
+}
+
+type CategoryHandler struct {
+	categoryService *service.CategoryService
+}
+
+func NewCategoryHandler(categoryService *service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService: categoryService,
+	}
+}
+
+// RegisterRoutes registers the category routes
+func (h *CategoryHandler) RegisterRoutes(router *gin.RouterGroup) {
+	canManage := middleware.RequirePermission(model.PermCategoriesManage)
+
+	categories := router.Group("/categories")
+	{
+		categories.POST("", canManage, h.CreateCategory)
+		categories.PUT("/:id", canManage, h.UpdateCategory)
+		categories.DELETE("/:id", canManage, h.DeleteCategory)
+		categories.GET("/:id", h.GetCategoryByID)
+		categories.GET("", h.GetAllCategories)
+	}
+}
+
+// CreateCategory handles the creation of a new category
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Category creation validation error: %v", err)
+		failBinding(c, err)
+		return
+	}
+	category := req.toModel()
+
+	if err := h.categoryService.CreateCategory(category); err != nil {
+		logger.Error("Failed to create category %s: %v", category.Name, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Category created successfully: ID %d by user ID %d", category.ID, c.GetUint("userID"))
+	c.JSON(http.StatusCreated, newCategoryResponse(category))
+}
+
+// UpdateCategory handles the update of an existing category
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, ok := parseCategoryID(c)
+	if !ok {
+		return
+	}
+
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Category update validation error for ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+	category := req.toModel()
+	category.ID = id
+
+	if err := h.categoryService.UpdateCategory(category); err != nil {
+		logger.Error("Failed to update category ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Category updated successfully: ID %d by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, newCategoryResponse(category))
+}
+
+// DeleteCategory handles the deletion of a category. The move_to query
+// parameter names the category its documents and services move to.
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, ok := parseCategoryID(c)
+	if !ok {
+		return
+	}
+
+	var moveTo *uint
+	if raw := c.Query("move_to"); raw != "" {
+		target, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			badRequest(c, "invalid_id", "Invalid move_to category ID format")
+			return
+		}
+		targetID := uint(target)
+		moveTo = &targetID
+	}
+
+	if err := h.categoryService.DeleteCategory(id, moveTo); err != nil {
+		logger.Error("Failed to delete category ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Category deleted successfully: ID %d by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
+
+// GetCategoryByID handles the retrieval of a category by its ID
+func (h *CategoryHandler) GetCategoryByID(c *gin.Context) {
+	id, ok := parseCategoryID(c)
+	if !ok {
+		return
+	}
+
+	category, err := h.categoryService.GetCategoryByID(id, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to get category ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newCategorySummaryResponse(category))
+}
+
+// GetAllCategories handles the retrieval of a page of categories with how
+// many documents and services are in each
+func (h *CategoryHandler) GetAllCategories(c *gin.Context) {
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.categoryService.GetAllCategories(actorFrom(c), q)
+	if err != nil {
+		logger.Error("Failed to get categories: %v", err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newCategorySummaryResponse))
+}
+
+func parseCategoryID(c *gin.Context) (uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid category ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid category ID format")
+		return 0, false
+	}
+	return uint(id), true
+}
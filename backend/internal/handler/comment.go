@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"techdocs/internal/model"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CommentRequest is the body for creating or editing a comment. ParentID
+// is only read on creation.
+type CommentRequest struct {
+	Content         string `json:"content" binding:"required"`
+	ParentID        *uint  `json:"parent_id"`
+	AnchorHeading   string `json:"anchor_heading"`
+	AnchorStartLine *int   `json:"anchor_start_line"`
+	AnchorEndLine   *int   `json:"anchor_end_line"`
+}
+
+type CommentHandler struct {
+	commentService *service.CommentService
+}
+
+func NewCommentHandler(commentService *service.CommentService) *CommentHandler {
+	return &CommentHandler{
+		commentService: commentService,
+	}
+}
+
+// RegisterRoutes registers the comment routes
+func (h *CommentHandler) RegisterRoutes(router *gin.RouterGroup) {
+	comments := router.Group("/documents/:id/comments")
+	{
+		comments.GET("", h.GetComments)
+		comments.POST("", h.CreateComment)
+		comments.GET("/:commentID", h.GetComment)
+		comments.PUT("/:commentID", h.UpdateComment)
+		comments.DELETE("/:commentID", h.DeleteComment)
+		comments.POST("/:commentID/resolve", h.ResolveThread)
+		comments.POST("/:commentID/unresolve", h.UnresolveThread)
+	}
+}
+
+// GetComments handles the retrieval of a page of a document's comment
+// threads
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return
+	}
+
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.commentService.GetThreads(uint(id), actorFrom(c), q)
+	if err != nil {
+		logger.Error("Failed to get comments for document ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newCommentResponse))
+}
+
+// CreateComment handles adding a comment or reply to a document
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Comment validation error for document ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+
+	comment := model.Comment{
+		Content:         req.Content,
+		ParentID:        req.ParentID,
+		AnchorHeading:   req.AnchorHeading,
+		AnchorStartLine: req.AnchorStartLine,
+		AnchorEndLine:   req.AnchorEndLine,
+	}
+	actor := actorFrom(c)
+	if err := h.commentService.CreateComment(uint(id), &comment, actor); err != nil {
+		logger.Error("Failed to comment on document ID %d by user ID %d: %v", id, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Comment created successfully: ID %d on document ID %d by user ID %d", comment.ID, id, actor.UserID)
+	c.JSON(http.StatusCreated, newCommentResponse(&comment))
+}
+
+// GetComment handles the retrieval of a comment with its replies
+func (h *CommentHandler) GetComment(c *gin.Context) {
+	id, commentID, ok := parseDocumentAndCommentID(c)
+	if !ok {
+		return
+	}
+
+	comment, err := h.commentService.GetComment(id, commentID, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to get comment ID %d on document ID %d: %v", commentID, id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newCommentResponse(comment))
+}
+
+// UpdateComment handles editing a comment
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	id, commentID, ok := parseDocumentAndCommentID(c)
+	if !ok {
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Comment validation error for comment ID %d: %v", commentID, err)
+		failBinding(c, err)
+		return
+	}
+
+	comment := model.Comment{
+		Content:         req.Content,
+		AnchorHeading:   req.AnchorHeading,
+		AnchorStartLine: req.AnchorStartLine,
+		AnchorEndLine:   req.AnchorEndLine,
+	}
+	comment.ID = commentID
+	actor := actorFrom(c)
+	if err := h.commentService.UpdateComment(id, &comment, actor); err != nil {
+		logger.Error("Failed to update comment ID %d by user ID %d: %v", commentID, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Comment updated successfully: ID %d by user ID %d", commentID, actor.UserID)
+	c.JSON(http.StatusOK, newCommentResponse(&comment))
+}
+
+// DeleteComment handles the deletion of a comment and its replies
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	id, commentID, ok := parseDocumentAndCommentID(c)
+	if !ok {
+		return
+	}
+
+	actor := actorFrom(c)
+	if err := h.commentService.DeleteComment(id, commentID, actor); err != nil {
+		logger.Error("Failed to delete comment ID %d by user ID %d: %v", commentID, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Comment deleted successfully: ID %d by user ID %d", commentID, actor.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// ResolveThread handles marking a comment thread as resolved
+func (h *CommentHandler) ResolveThread(c *gin.Context) {
+	h.setResolved(c, true)
+}
+
+// UnresolveThread handles reopening a resolved comment thread
+func (h *CommentHandler) UnresolveThread(c *gin.Context) {
+	h.setResolved(c, false)
+}
+
+func (h *CommentHandler) setResolved(c *gin.Context, resolved bool) {
+	id, commentID, ok := parseDocumentAndCommentID(c)
+	if !ok {
+		return
+	}
+
+	actor := actorFrom(c)
+	comment, err := h.commentService.SetThreadResolved(id, commentID, resolved, actor)
+	if err != nil {
+		logger.Error("Failed to set resolved=%t on comment ID %d by user ID %d: %v", resolved, commentID, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Comment thread ID %d resolved=%t by user ID %d", commentID, resolved, actor.UserID)
+	c.JSON(http.StatusOK, newCommentResponse(comment))
+}
+
+// parseDocumentAndCommentID parses the id and commentID path parameters,
+// writing a 400 response when either is malformed
+func parseDocumentAndCommentID(c *gin.Context) (uint, uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return 0, 0, false
+	}
+
+	commentIDStr := c.Param("commentID")
+	commentID, err := strconv.ParseUint(commentIDStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid comment ID format: %s", commentIDStr)
+		badRequest(c, "invalid_id", "Invalid comment ID format")
+		return 0, 0, false
+	}
+
+	return uint(id), uint(commentID), true
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"techdocs/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// actorFrom builds the acting user from the claims set by AuthMiddleware
+// and the permissions set by LoadPermissions
+func actorFrom(c *gin.Context) service.Actor {
+	return service.Actor{
+		UserID:      c.GetUint("userID"),
+		Role:        c.GetString("role"),
+		Permissions: c.GetStringSlice("permissions"),
+	}
+}
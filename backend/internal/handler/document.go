@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"techdocs/internal/middleware"
 	"techdocs/internal/model"
 	"techdocs/internal/service"
 	"techdocs/pkg/logger"
@@ -10,6 +13,77 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// DocumentRequest is the body for creating or updating a document. Tags
+// are given by name; missing tags are created and on update the given tags
+// replace the document's. Space and parent only apply on creation, moving
+// a document has its own route. Content is limited to a million
+// characters.
+type DocumentRequest struct {
+	Title       string   `json:"title" binding:"required,max=255"`
+	Description string   `json:"description" binding:"max=1000"`
+	Content     string   `json:"content" binding:"max=1000000"`
+	Type        string   `json:"type" binding:"required,oneof=document service diagram use_case"`
+	CategoryID  *uint    `json:"category_id"`
+	Tags        []string `json:"tags" binding:"max=50,dive,required,max=255"`
+	ServiceID   *uint    `json:"service_id"`
+	TeamID      *uint    `json:"team_id"`
+	Restricted  bool     `json:"restricted"`
+	SpaceID     *uint    `json:"space_id"`
+	ParentID    *uint    `json:"parent_id"`
+	Revision    uint     `json:"revision"`
+}
+
+// toModel turns the request into the document it describes
+func (r *DocumentRequest) toModel() *model.Document {
+	doc := &model.Document{
+		Title:       r.Title,
+		Description: r.Description,
+		Content:     r.Content,
+		Type:        r.Type,
+		CategoryID:  r.CategoryID,
+		Tags:        make([]model.Tag, len(r.Tags)),
+		ServiceID:   r.ServiceID,
+		TeamID:      r.TeamID,
+		Restricted:  r.Restricted,
+		SpaceID:     r.SpaceID,
+		ParentID:    r.ParentID,
+		Revision:    r.Revision,
+	}
+	for i, name := range r.Tags {
+		doc.Tags[i] = model.Tag{Name: name}
+	}
+	return doc
+}
+
+// newDocumentRequest returns the request that would update a document to
+// its current state
+func newDocumentRequest(doc *model.Document) DocumentRequest {
+	req := DocumentRequest{
+		Title:       doc.Title,
+		Description: doc.Description,
+		Content:     doc.Content,
+		Type:        doc.Type,
+		CategoryID:  doc.CategoryID,
+		Tags:        make([]string, len(doc.Tags)),
+		ServiceID:   doc.ServiceID,
+		TeamID:      doc.TeamID,
+		Restricted:  doc.Restricted,
+		SpaceID:     doc.SpaceID,
+		ParentID:    doc.ParentID,
+		Revision:    doc.Revision,
+	}
+	for i, tag := range doc.Tags {
+		req.Tags[i] = tag.Name
+	}
+	return req
+}
+
+// patchableDocumentFields are the members of a DocumentRequest a PATCH may
+// change; the place in the page tree is changed by moving the document
+var patchableDocumentFields = []string{
+	"title", "description", "content", "type", "category_id", "tags", "service_id", "team_id", "restricted",
+}
+
 type DocumentHandler struct {
 	documentService *service.DocumentService
 }
@@ -22,69 +96,174 @@ func NewDocumentHandler(documentService *service.DocumentService) *DocumentHandl
 
 // RegisterRoutes registers the document routes
 func (h *DocumentHandler) RegisterRoutes(router *gin.RouterGroup) {
+	canWrite := middleware.RequirePermission(model.PermDocumentsWrite)
+
 	documents := router.Group("/documents")
 	{
-		documents.POST("", h.CreateDocument)
-		documents.PUT("/:id", h.UpdateDocument)
-		documents.DELETE("/:id", h.DeleteDocument)
+		documents.POST("", canWrite, h.CreateDocument)
+		documents.PUT("/:id", canWrite, h.UpdateDocument)
+		documents.PATCH("/:id", canWrite, h.PatchDocument)
+		documents.DELETE("/:id", canWrite, h.DeleteDocument)
 		documents.GET("/:id", h.GetDocumentByID)
+		documents.GET("/:id/versions", h.GetDocumentVersions)
+		documents.GET("/:id/versions/:version", h.GetDocumentVersion)
+		documents.POST("/:id/versions/:version/restore", canWrite, h.RestoreDocumentVersion)
+		documents.GET("/:id/diff", h.DiffDocument)
+		documents.POST("/:id/move", canWrite, h.MoveDocument)
+		documents.GET("/:id/reviewers", h.GetReviewers)
+		documents.PUT("/:id/reviewers", canWrite, h.SetReviewers)
+		documents.POST("/:id/submit", canWrite, h.SubmitDocument)
+		documents.POST("/:id/withdraw", canWrite, h.WithdrawDocument)
+		documents.POST("/:id/approve", h.ApproveDocument)
+		documents.POST("/:id/request-changes", h.RequestChanges)
+		documents.POST("/:id/review-comment", h.CommentOnReview)
+		documents.POST("/:id/publish", canWrite, h.PublishDocument)
+		documents.POST("/:id/archive", canWrite, h.ArchiveDocument)
+		documents.POST("/:id/reopen", canWrite, h.ReopenDocument)
+		documents.GET("/:id/permissions", h.GetDocumentPermissions)
+		documents.PUT("/:id/permissions/:userID", canWrite, h.GrantDocumentPermission)
+		documents.DELETE("/:id/permissions/:userID", canWrite, h.RevokeDocumentPermission)
+		documents.PUT("/:id/permissions/teams/:teamID", canWrite, h.GrantTeamDocumentPermission)
+		documents.DELETE("/:id/permissions/teams/:teamID", canWrite, h.RevokeTeamDocumentPermission)
 		documents.GET("", h.GetAllDocuments)
 		documents.GET("/author/:authorID", h.GetDocumentsByAuthor)
 		documents.GET("/category/:category", h.GetDocumentsByCategory)
 	}
+
+	router.GET("/reviews/pending", h.GetPendingReviews)
 }
 
 // CreateDocument handles the creation of a new document
 func (h *DocumentHandler) CreateDocument(c *gin.Context) {
-	var doc model.Document
-	if err := c.ShouldBindJSON(&doc); err != nil {
+	var req DocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Document creation validation error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		failBinding(c, err)
 		return
 	}
+	doc := req.toModel()
 
 	// Get user ID from context
 	userID, exists := c.Get("userID")
 	if !exists {
 		logger.Error("User not authenticated")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		middleware.AbortWithProblem(c, http.StatusUnauthorized, "unauthenticated", "User not authenticated")
 		return
 	}
 
 	// Set the author ID
 	doc.AuthorID = userID.(uint)
 
-	if err := h.documentService.CreateDocument(&doc); err != nil {
+	if err := h.documentService.CreateDocument(doc, actorFrom(c)); err != nil {
 		logger.Error("Failed to create document for user ID %d: %v", userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
 	logger.Info("Document created successfully: ID %d by user ID %d", doc.ID, userID)
-	c.JSON(http.StatusCreated, doc)
+	c.JSON(http.StatusCreated, newDocumentResponse(doc))
 }
 
 // UpdateDocument handles the update of an existing document
 func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
-	id := c.Param("id")
-	var doc model.Document
-	if err := c.ShouldBindJSON(&doc); err != nil {
-		logger.Error("Document update validation error for ID %s: %v", id, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return
+	}
+
+	var req DocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Document update validation error for ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+	doc := req.toModel()
+
+	revision, ok := expectedRevision(c, doc.Revision)
+	if !ok {
+		logger.Error("Document update for ID %d without revision", id)
+		middleware.AbortWithProblem(c, http.StatusPreconditionRequired, "revision_required", "If-Match header or revision field is required")
+		return
+	}
+
+	userID := c.GetUint("userID")
+	doc.ID = uint(id)
+	doc.Revision = revision
+
+	if err := h.documentService.UpdateDocument(doc, actorFrom(c)); err != nil {
+		logger.Error("Failed to update document ID %d for user ID %d: %v", id, userID, err)
+		failUpdate(c, err, h.currentDocument(c, uint(id)))
+		return
+	}
+
+	logger.Info("Document updated successfully: ID %d by user ID %d", id, userID)
+	c.Header("ETag", etag(doc.Revision))
+	c.JSON(http.StatusOK, newDocumentResponse(doc))
+}
+
+// PatchDocument handles a partial update of a document, given as a JSON
+// Merge Patch or a JSON Patch of its DocumentRequest. Fields the patch
+// leaves alone keep their stored values. The revision the patch is based
+// on is given by If-Match or by setting the revision member.
+func (h *DocumentHandler) PatchDocument(c *gin.Context) {
+	id, ok := parseDocumentID(c)
+	if !ok {
+		return
+	}
+
+	existing, err := h.documentService.GetDocumentByID(id, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to get document ID %d for patch: %v", id, err)
+		fail(c, err)
+		return
+	}
+	current := newDocumentRequest(existing)
+	current.Revision = 0
+
+	var req DocumentRequest
+	changed, ok := patchRequest(c, current, &req, append(patchableDocumentFields, revisionField)...)
+	if !ok {
+		return
+	}
+	revision, ok := patchRevision(c, req.Revision)
+	if !ok {
+		return
+	}
+	if len(changed) == 0 {
+		c.Header("ETag", etag(existing.Revision))
+		c.JSON(http.StatusOK, newDocumentResponse(existing))
 		return
 	}
 
 	userID := c.GetUint("userID")
-	doc.AuthorID = userID
+	doc := req.toModel()
+	doc.ID = id
+	doc.Revision = revision
 
-	if err := h.documentService.UpdateDocument(&doc); err != nil {
-		logger.Error("Failed to update document ID %s for user ID %d: %v", id, userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.documentService.UpdateDocument(doc, actorFrom(c)); err != nil {
+		logger.Error("Failed to patch document ID %d for user ID %d: %v", id, userID, err)
+		failUpdate(c, err, h.currentDocument(c, id))
 		return
 	}
 
-	logger.Info("Document updated successfully: ID %s by user ID %d", id, userID)
-	c.JSON(http.StatusOK, doc)
+	logger.Info("Document patched successfully: ID %d by user ID %d, changed %s", id, userID, strings.Join(changed, ", "))
+	c.Header("ETag", etag(doc.Revision))
+	c.JSON(http.StatusOK, newDocumentResponse(doc))
+}
+
+// currentDocument returns a function looking up the current state of a
+// document for a revision conflict
+func (h *DocumentHandler) currentDocument(c *gin.Context, id uint) func() any {
+	return func() any {
+		existing, _ := h.documentService.GetDocumentByID(id, actorFrom(c))
+		if existing == nil {
+			return nil
+		}
+		return newDocumentResponse(existing)
+	}
 }
 
 // DeleteDocument handles the deletion of a document
@@ -93,13 +272,13 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
 		logger.Error("Invalid document ID format: %s", idStr)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID format"})
+		badRequest(c, "invalid_id", "Invalid document ID format")
 		return
 	}
 
-	if err := h.documentService.DeleteDocument(uint(id)); err != nil {
+	if err := h.documentService.DeleteDocument(uint(id), actorFrom(c)); err != nil {
 		logger.Error("Failed to delete document ID %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
@@ -107,7 +286,8 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Document deleted successfully"})
 }
 
-// GetDocumentByID handles the retrieval of a document by its ID
+// GetDocumentByID handles the retrieval of a document by its ID, along
+// with its review state
 func (h *DocumentHandler) GetDocumentByID(c *gin.Context) {
 	idStr := c.Param("id")
 	logger.Info("Received document ID parameter: %s", idStr)
@@ -118,64 +298,374 @@ func (h *DocumentHandler) GetDocumentByID(c *gin.Context) {
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
 		logger.Error("Invalid document ID format: %s", idStr)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID format"})
+		badRequest(c, "invalid_id", "Invalid document ID format")
 		return
 	}
 
 	logger.Info("Parsed document ID: %d", id)
 
-	doc, err := h.documentService.GetDocumentByID(uint(id))
+	doc, err := h.documentService.GetDocumentDetails(uint(id), actorFrom(c))
 	if err != nil {
 		logger.Error("Failed to get document ID %d: %v", id, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		fail(c, err)
 		return
 	}
 
 	logger.Info("Successfully retrieved document: %d", id)
-	c.JSON(http.StatusOK, doc)
+	c.Header("ETag", etag(doc.Revision))
+	c.JSON(http.StatusOK, newDocumentDetailsResponse(doc))
 }
 
-// GetAllDocuments handles the retrieval of all documents
+// GetAllDocuments handles the retrieval of a page of documents
 func (h *DocumentHandler) GetAllDocuments(c *gin.Context) {
-	docs, err := h.documentService.GetAllDocuments()
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.documentService.GetAllDocuments(actorFrom(c), q)
 	if err != nil {
 		logger.Error("Failed to get all documents: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, docs)
+	c.JSON(http.StatusOK, mapPage(page, newDocumentResponse))
 }
 
-// GetDocumentsByAuthor handles the retrieval of documents by author ID
+// GetDocumentsByAuthor handles the retrieval of a page of documents by
+// author ID
 func (h *DocumentHandler) GetDocumentsByAuthor(c *gin.Context) {
 	authorIDStr := c.Param("authorID")
 	authorID, err := strconv.ParseUint(authorIDStr, 10, 32)
 	if err != nil {
 		logger.Error("Invalid author ID format: %s", authorIDStr)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID format"})
+		badRequest(c, "invalid_id", "Invalid author ID format")
 		return
 	}
 
-	docs, err := h.documentService.GetDocumentsByAuthor(uint(authorID))
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+	id := uint(authorID)
+	q.Filter.AuthorID = &id
+
+	page, err := h.documentService.GetAllDocuments(actorFrom(c), q)
 	if err != nil {
 		logger.Error("Failed to get documents for author ID %d: %v", authorID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, docs)
+	c.JSON(http.StatusOK, mapPage(page, newDocumentResponse))
 }
 
-// GetDocumentsByCategory handles the retrieval of documents by category
+// GetDocumentsByCategory handles the retrieval of a page of documents by
+// category, given by slug or name
 func (h *DocumentHandler) GetDocumentsByCategory(c *gin.Context) {
 	category := c.Param("category")
-	docs, err := h.documentService.GetDocumentsByCategory(category)
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+	q.Filter.Category = category
+
+	page, err := h.documentService.GetAllDocuments(actorFrom(c), q)
 	if err != nil {
 		logger.Error("Failed to get documents for category %s: %v", category, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newDocumentResponse))
+}
+
+// GetDocumentVersions handles the retrieval of a document's version history
+func (h *DocumentHandler) GetDocumentVersions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return
+	}
+
+	versions, err := h.documentService.GetDocumentVersions(uint(id), actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to get versions for document ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapSlice(versions, newDocumentVersionResponse))
+}
+
+// GetDocumentVersion handles the retrieval of a single document version
+func (h *DocumentHandler) GetDocumentVersion(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return
+	}
+
+	versionStr := c.Param("version")
+	version, err := strconv.Atoi(versionStr)
+	if err != nil || version < 1 {
+		logger.Error("Invalid version number format: %s", versionStr)
+		badRequest(c, "invalid_parameter", "Invalid version number format")
+		return
+	}
+
+	v, err := h.documentService.GetDocumentVersion(uint(id), version, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to get version %d of document ID %d: %v", version, id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newDocumentVersionResponse(v))
+}
+
+// RestoreDocumentVersion handles reverting a document to a stored version
+func (h *DocumentHandler) RestoreDocumentVersion(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return
+	}
+
+	versionStr := c.Param("version")
+	version, err := strconv.Atoi(versionStr)
+	if err != nil || version < 1 {
+		logger.Error("Invalid version number format: %s", versionStr)
+		badRequest(c, "invalid_parameter", "Invalid version number format")
+		return
+	}
+
+	userID := c.GetUint("userID")
+	doc, err := h.documentService.RestoreDocumentVersion(uint(id), version, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to restore version %d of document ID %d for user ID %d: %v", version, id, userID, err)
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, docs)
+	logger.Info("Document ID %d restored to version %d by user ID %d", id, version, userID)
+	c.Header("ETag", etag(doc.Revision))
+	c.JSON(http.StatusOK, newDocumentResponse(doc))
+}
+
+// DiffDocument handles the comparison of two revisions of a document.
+// The from and to query parameters take a version number or "current";
+// to defaults to the live document.
+func (h *DocumentHandler) DiffDocument(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return
+	}
+
+	from, err := parseRevision(c.Query("from"))
+	if err != nil || c.Query("from") == "" {
+		logger.Error("Invalid from revision for document ID %d: %q", id, c.Query("from"))
+		badRequest(c, "invalid_parameter", "Invalid or missing from revision")
+		return
+	}
+	to, err := parseRevision(c.Query("to"))
+	if err != nil {
+		logger.Error("Invalid to revision for document ID %d: %q", id, c.Query("to"))
+		badRequest(c, "invalid_parameter", "Invalid to revision")
+		return
+	}
+
+	d, err := h.documentService.DiffDocument(uint(id), from, to, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to diff document ID %d from %d to %d: %v", id, from, to, err)
+		fail(c, err)
+		return
+	}
+
+	if c.Query("format") == "diff" || strings.Contains(c.GetHeader("Accept"), "text/x-diff") {
+		c.Data(http.StatusOK, "text/x-diff; charset=utf-8", []byte(d.Unified()))
+		return
+	}
+
+	c.JSON(http.StatusOK, d)
+}
+
+// parseRevision parses a version number, mapping "current" and the empty
+// string to 0, the live document
+func parseRevision(s string) (int, error) {
+	if s == "" || s == "current" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, errors.New("invalid revision")
+	}
+	return n, nil
+}
+
+// GetDocumentPermissions handles the retrieval of the grants on a document
+func (h *DocumentHandler) GetDocumentPermissions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return
+	}
+
+	permissions, err := h.documentService.GetDocumentPermissions(uint(id), actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to get permissions of document ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapSlice(permissions, newDocumentPermissionResponse))
+}
+
+// GrantPermissionRequest is the body of a permission grant
+type GrantPermissionRequest struct {
+	Level string `json:"level" binding:"required,oneof=viewer editor owner"`
+}
+
+// GrantDocumentPermission handles granting a user access to a document
+func (h *DocumentHandler) GrantDocumentPermission(c *gin.Context) {
+	id, userID, ok := parseDocumentAndUserID(c)
+	if !ok {
+		return
+	}
+
+	var req GrantPermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Permission grant validation error for document ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+
+	actor := actorFrom(c)
+	permission, err := h.documentService.GrantDocumentPermission(id, userID, req.Level, actor)
+	if err != nil {
+		logger.Error("Failed to grant %s on document ID %d to user ID %d by user ID %d: %v", req.Level, id, userID, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("User ID %d granted %s on document ID %d by user ID %d", userID, req.Level, id, actor.UserID)
+	c.JSON(http.StatusOK, newDocumentPermissionResponse(permission))
+}
+
+// RevokeDocumentPermission handles removing a user's access to a document
+func (h *DocumentHandler) RevokeDocumentPermission(c *gin.Context) {
+	id, userID, ok := parseDocumentAndUserID(c)
+	if !ok {
+		return
+	}
+
+	actor := actorFrom(c)
+	if err := h.documentService.RevokeDocumentPermission(id, userID, actor); err != nil {
+		logger.Error("Failed to revoke permission on document ID %d from user ID %d by user ID %d: %v", id, userID, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Permission on document ID %d revoked from user ID %d by user ID %d", id, userID, actor.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Permission revoked successfully"})
+}
+
+// GrantTeamDocumentPermission handles granting a team access to a document
+func (h *DocumentHandler) GrantTeamDocumentPermission(c *gin.Context) {
+	id, teamID, ok := parseDocumentAndTeamID(c)
+	if !ok {
+		return
+	}
+
+	var req GrantPermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Permission grant validation error for document ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+
+	actor := actorFrom(c)
+	permission, err := h.documentService.GrantTeamDocumentPermission(id, teamID, req.Level, actor)
+	if err != nil {
+		logger.Error("Failed to grant %s on document ID %d to team ID %d by user ID %d: %v", req.Level, id, teamID, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Team ID %d granted %s on document ID %d by user ID %d", teamID, req.Level, id, actor.UserID)
+	c.JSON(http.StatusOK, newDocumentPermissionResponse(permission))
+}
+
+// RevokeTeamDocumentPermission handles removing a team's access to a document
+func (h *DocumentHandler) RevokeTeamDocumentPermission(c *gin.Context) {
+	id, teamID, ok := parseDocumentAndTeamID(c)
+	if !ok {
+		return
+	}
+
+	actor := actorFrom(c)
+	if err := h.documentService.RevokeTeamDocumentPermission(id, teamID, actor); err != nil {
+		logger.Error("Failed to revoke permission on document ID %d from team ID %d by user ID %d: %v", id, teamID, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Permission on document ID %d revoked from team ID %d by user ID %d", id, teamID, actor.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Permission revoked successfully"})
+}
+
+// parseDocumentAndTeamID parses the id and teamID path parameters, writing
+// a 400 response when either is malformed
+func parseDocumentAndTeamID(c *gin.Context) (uint, uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return 0, 0, false
+	}
+
+	teamIDStr := c.Param("teamID")
+	teamID, err := strconv.ParseUint(teamIDStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid team ID format: %s", teamIDStr)
+		badRequest(c, "invalid_id", "Invalid team ID format")
+		return 0, 0, false
+	}
+
+	return uint(id), uint(teamID), true
+}
+
+// parseDocumentAndUserID parses the id and userID path parameters, writing
+// a 400 response when either is malformed
+func parseDocumentAndUserID(c *gin.Context) (uint, uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return 0, 0, false
+	}
+
+	userIDStr := c.Param("userID")
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid user ID format: %s", userIDStr)
+		badRequest(c, "invalid_id", "Invalid user ID format")
+		return 0, 0, false
+	}
+
+	return uint(id), uint(userID), true
 }
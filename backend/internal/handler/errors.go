@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http"
+	"techdocs/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fail hands an error to middleware.Errors, which answers with the
+// matching problem response
+func fail(c *gin.Context, err error) {
+	_ = c.Error(err)
+}
+
+// failBinding hands a request body that couldn't be bound or validated
+// to middleware.Errors
+func failBinding(c *gin.Context, err error) {
+	_ = c.Error(err).SetType(gin.ErrorTypeBind)
+}
+
+// badRequest writes a 400 problem response for a malformed request that
+// never reached a service
+func badRequest(c *gin.Context, code, detail string) {
+	middleware.AbortWithProblem(c, http.StatusBadRequest, code, detail)
+}
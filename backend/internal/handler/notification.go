@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PreferencesRequest is the body for updating notification preferences
+type PreferencesRequest struct {
+	Digest string `json:"digest" binding:"required,oneof=immediate daily"`
+}
+
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// RegisterRoutes registers the notification routes
+func (h *NotificationHandler) RegisterRoutes(router *gin.RouterGroup) {
+	notifications := router.Group("/notifications")
+	{
+		notifications.GET("", h.GetNotifications)
+		notifications.POST("/read", h.MarkAllRead)
+		notifications.POST("/:id/read", h.MarkRead)
+		notifications.GET("/preferences", h.GetPreferences)
+		notifications.PUT("/preferences", h.UpdatePreferences)
+	}
+}
+
+// GetNotifications handles the retrieval of a page of the current user's
+// notifications along with the unread count. Pass unread=true for unread
+// notifications only.
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	actor := actorFrom(c)
+	list, err := h.notificationService.GetNotifications(actor, q)
+	if err != nil {
+		logger.Error("Failed to get notifications for user ID %d: %v", actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newNotificationListResponse(list))
+}
+
+// MarkRead handles marking one notification as read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid notification ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid notification ID format")
+		return
+	}
+
+	actor := actorFrom(c)
+	if err := h.notificationService.MarkRead(uint(id), actor); err != nil {
+		logger.Error("Failed to mark notification ID %d read for user ID %d: %v", id, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+// MarkAllRead handles marking all of the current user's notifications as
+// read
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	actor := actorFrom(c)
+	count, err := h.notificationService.MarkAllRead(actor)
+	if err != nil {
+		logger.Error("Failed to mark notifications read for user ID %d: %v", actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notifications marked as read", "marked": count})
+}
+
+// GetPreferences handles the retrieval of the current user's notification
+// preferences
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	actor := actorFrom(c)
+	digest, err := h.notificationService.GetDigest(actor)
+	if err != nil {
+		logger.Error("Failed to get notification preferences for user ID %d: %v", actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"digest": digest})
+}
+
+// UpdatePreferences handles changing whether the current user hears about
+// notifications as they happen or in a daily digest
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	var req PreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Notification preferences validation error: %v", err)
+		failBinding(c, err)
+		return
+	}
+
+	actor := actorFrom(c)
+	if err := h.notificationService.SetDigest(req.Digest, actor); err != nil {
+		logger.Error("Failed to update notification preferences for user ID %d: %v", actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Notification preferences updated for user ID %d: digest %s", actor.UserID, req.Digest)
+	c.JSON(http.StatusOK, gin.H{"digest": req.Digest})
+}
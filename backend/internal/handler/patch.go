@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"techdocs/internal/middleware"
+	"techdocs/pkg/logger"
+	"techdocs/pkg/patch"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Media types of the patch formats accepted by PATCH routes. Plain JSON
+// is read as a merge patch.
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// revisionField is the request member a patch may set, instead of sending
+// If-Match, to name the revision it is based on
+const revisionField = "revision"
+
+// patchRequest applies the patch in the request body to current, a request
+// body filled in from the stored resource, and binds and validates the
+// result into patched. Only the members named in patchable may change.
+// It returns the names of the members that changed, leaving out the
+// revision, or false once it has answered the request.
+func patchRequest(c *gin.Context, current, patched any, patchable ...string) ([]string, bool) {
+	body, err := c.GetRawData()
+	if err != nil {
+		failBinding(c, err)
+		return nil, false
+	}
+	before, err := json.Marshal(current)
+	if err != nil {
+		fail(c, err)
+		return nil, false
+	}
+
+	var after []byte
+	switch c.ContentType() {
+	case mergePatchType, binding.MIMEJSON:
+		after, err = patch.Merge(before, body)
+	case jsonPatchType:
+		after, err = patch.Apply(before, body)
+	default:
+		middleware.AbortWithProblem(c, http.StatusUnsupportedMediaType, "unsupported_media_type",
+			"PATCH requests take "+mergePatchType+" or "+jsonPatchType)
+		return nil, false
+	}
+	if err != nil {
+		logger.Error("Failed to apply patch to %s: %v", c.Request.URL.Path, err)
+		fail(c, err)
+		return nil, false
+	}
+
+	changed, err := patch.Changed(before, after)
+	if err != nil {
+		fail(c, err)
+		return nil, false
+	}
+	if fixed := unpatchable(changed, patchable); len(fixed) > 0 {
+		badRequest(c, "field_not_patchable", strings.Join(fixed, ", ")+" can't be changed with PATCH")
+		return nil, false
+	}
+
+	if err := json.Unmarshal(after, patched); err != nil {
+		failBinding(c, err)
+		return nil, false
+	}
+	if err := binding.Validator.ValidateStruct(patched); err != nil {
+		failBinding(c, err)
+		return nil, false
+	}
+
+	edits := changed[:0]
+	for _, name := range changed {
+		if name != revisionField {
+			edits = append(edits, name)
+		}
+	}
+	return edits, true
+}
+
+// unpatchable returns the changed members that aren't patchable
+func unpatchable(changed, patchable []string) []string {
+	var fixed []string
+	for _, name := range changed {
+		allowed := false
+		for _, p := range patchable {
+			if name == p {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			fixed = append(fixed, name)
+		}
+	}
+	return fixed
+}
+
+// patchRevision returns the revision a patch is based on, taken from the
+// If-Match header or, without one, from the revision the patch sets. With
+// neither the request is answered with a 428, as an update would be, and a
+// malformed header with a 400.
+func patchRevision(c *gin.Context, patched uint) (uint, bool) {
+	if c.GetHeader("If-Match") == "" {
+		if patched == 0 {
+			middleware.AbortWithProblem(c, http.StatusPreconditionRequired, "revision_required", "If-Match header or revision field is required")
+			return 0, false
+		}
+		return patched, true
+	}
+	revision, ok := expectedRevision(c, 0)
+	if !ok {
+		badRequest(c, "invalid_revision", "If-Match must be the ETag of the resource")
+		return 0, false
+	}
+	return revision, true
+}
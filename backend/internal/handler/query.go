@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"strconv"
+	"techdocs/internal/repository"
+	"techdocs/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseListQuery reads the common list parameters: page, limit, cursor,
+// sort and the filters type, category, tag, role, status, resolved, unread,
+// service_id, space_id, author_id, team_id, created_after, created_before,
+// updated_after and updated_before. It writes a 400 response when one of
+// them is malformed.
+func parseListQuery(c *gin.Context) (repository.ListQuery, bool) {
+	q := repository.ListQuery{
+		Cursor: c.Query("cursor"),
+		Sort:   c.Query("sort"),
+		Filter: repository.ListFilter{
+			Type:     c.Query("type"),
+			Category: c.Query("category"),
+			Tag:      c.Query("tag"),
+			Role:     c.Query("role"),
+			Status:   c.Query("status"),
+		},
+	}
+
+	var ok bool
+	if q.Page, ok = intQuery(c, "page"); !ok {
+		return q, false
+	}
+	if q.Limit, ok = intQuery(c, "limit"); !ok {
+		return q, false
+	}
+
+	if q.Filter.Resolved, ok = boolQuery(c, "resolved"); !ok {
+		return q, false
+	}
+	unread, ok := boolQuery(c, "unread")
+	if !ok {
+		return q, false
+	}
+	q.Filter.Unread = unread != nil && *unread
+
+	ids := []struct {
+		name  string
+		label string
+		dest  **uint
+	}{
+		{"service_id", "service ID", &q.Filter.ServiceID},
+		{"space_id", "space ID", &q.Filter.SpaceID},
+		{"author_id", "author ID", &q.Filter.AuthorID},
+		{"team_id", "team ID", &q.Filter.TeamID},
+	}
+	for _, p := range ids {
+		if *p.dest, ok = idQuery(c, p.name, p.label); !ok {
+			return q, false
+		}
+	}
+
+	dates := []struct {
+		name string
+		dest **time.Time
+	}{
+		{"created_after", &q.Filter.CreatedAfter},
+		{"created_before", &q.Filter.CreatedBefore},
+		{"updated_after", &q.Filter.UpdatedAfter},
+		{"updated_before", &q.Filter.UpdatedBefore},
+	}
+	for _, p := range dates {
+		if *p.dest, ok = dateQuery(c, p.name); !ok {
+			return q, false
+		}
+	}
+
+	return q, true
+}
+
+// intQuery parses an optional positive integer query parameter
+func intQuery(c *gin.Context, name string) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, true
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		logger.Error("Invalid %s: %s", name, value)
+		badRequest(c, "invalid_parameter", "Invalid "+name)
+		return 0, false
+	}
+	return n, true
+}
+
+// boolQuery parses an optional true/false query parameter
+func boolQuery(c *gin.Context, name string) (*bool, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Error("Invalid %s: %s", name, value)
+		badRequest(c, "invalid_parameter", "Invalid "+name+", use true or false")
+		return nil, false
+	}
+	return &b, true
+}
+
+// idQuery parses an optional ID query parameter
+func idQuery(c *gin.Context, name, label string) (*uint, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		logger.Error("Invalid %s format: %s", label, value)
+		badRequest(c, "invalid_parameter", "Invalid "+label+" format")
+		return nil, false
+	}
+	result := uint(id)
+	return &result, true
+}
+
+// dateQuery parses an optional RFC 3339 timestamp or YYYY-MM-DD date query
+// parameter. Plain dates are taken as midnight server time.
+func dateQuery(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.ParseInLocation("2006-01-02", value, time.Local)
+	}
+	if err != nil {
+		logger.Error("Invalid %s: %s", name, value)
+		badRequest(c, "invalid_parameter", "Invalid "+name+", use YYYY-MM-DD or RFC 3339")
+		return nil, false
+	}
+	return &t, true
+}
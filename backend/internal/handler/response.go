@@ -0,0 +1,662 @@
+package handler
+
+import (
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+	"techdocs/internal/service"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The response types below give the resources of the API a stable JSON
+// shape, independent of the database models and what GORM adds to them.
+
+// UserResponse is a user as returned to themselves and to admins
+type UserResponse struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Digest    string    `json:"digest"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserSummary identifies a user referenced by another resource
+type UserSummary struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+// Reference identifies a team, space or service referenced by another
+// resource
+type Reference struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// CategoryReference identifies the category of a document or service
+type CategoryReference struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// DocumentResponse is a document. DeletedAt is only set for documents in
+// the trash.
+type DocumentResponse struct {
+	ID               uint               `json:"id"`
+	Title            string             `json:"title"`
+	Description      string             `json:"description"`
+	Content          string             `json:"content"`
+	Type             string             `json:"type"`
+	Status           string             `json:"status"`
+	Tags             []string           `json:"tags"`
+	CategoryID       *uint              `json:"category_id"`
+	Category         *CategoryReference `json:"category,omitempty"`
+	AuthorID         uint               `json:"author_id"`
+	Author           *UserSummary       `json:"author,omitempty"`
+	ServiceID        *uint              `json:"service_id"`
+	Service          *Reference         `json:"service,omitempty"`
+	TeamID           *uint              `json:"team_id"`
+	Team             *Reference         `json:"team,omitempty"`
+	SpaceID          *uint              `json:"space_id"`
+	Space            *Reference         `json:"space,omitempty"`
+	ParentID         *uint              `json:"parent_id"`
+	Position         int                `json:"position"`
+	Restricted       bool               `json:"restricted"`
+	Revision         uint               `json:"revision"`
+	PublishedVersion *int               `json:"published_version,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+	DeletedAt        *time.Time         `json:"deleted_at,omitempty"`
+}
+
+// DocumentDetailsResponse is a document with its place in its space and,
+// for those taking part, its review
+type DocumentDetailsResponse struct {
+	DocumentResponse
+	Breadcrumbs []service.Breadcrumb `json:"breadcrumbs"`
+	Review      *ReviewStateResponse `json:"review,omitempty"`
+}
+
+// DocumentVersionResponse is a stored version of a document
+type DocumentVersionResponse struct {
+	DocumentID   uint      `json:"document_id"`
+	Version      int       `json:"version"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	Content      string    `json:"content"`
+	Type         string    `json:"type"`
+	CategoryID   *uint     `json:"category_id"`
+	Tags         []string  `json:"tags"`
+	ServiceID    *uint     `json:"service_id"`
+	CreatedBy    uint      `json:"created_by"`
+	RestoredFrom *int      `json:"restored_from,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DocumentPermissionResponse is a grant of access to a document, held by
+// either a user or a team
+type DocumentPermissionResponse struct {
+	DocumentID uint         `json:"document_id"`
+	UserID     *uint        `json:"user_id,omitempty"`
+	User       *UserSummary `json:"user,omitempty"`
+	TeamID     *uint        `json:"team_id,omitempty"`
+	Team       *Reference   `json:"team,omitempty"`
+	Level      string       `json:"level"`
+	GrantedBy  uint         `json:"granted_by"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+// ReviewerResponse is a reviewer of a document and their decision on the
+// current submission
+type ReviewerResponse struct {
+	DocumentID uint         `json:"document_id"`
+	UserID     uint         `json:"user_id"`
+	User       *UserSummary `json:"user,omitempty"`
+	Decision   string       `json:"decision"`
+	Comment    string       `json:"comment,omitempty"`
+	DecidedAt  *time.Time   `json:"decided_at,omitempty"`
+}
+
+// ReviewStateResponse is where a document stands in its review
+type ReviewStateResponse struct {
+	RequiredApprovals int                `json:"required_approvals"`
+	Approvals         int                `json:"approvals"`
+	Reviewers         []ReviewerResponse `json:"reviewers"`
+}
+
+// CommentResponse is a comment, with its replies when it starts a thread
+type CommentResponse struct {
+	ID              uint              `json:"id"`
+	DocumentID      uint              `json:"document_id"`
+	UserID          uint              `json:"user_id"`
+	User            *UserSummary      `json:"user,omitempty"`
+	ParentID        *uint             `json:"parent_id"`
+	Content         string            `json:"content"`
+	AnchorHeading   string            `json:"anchor_heading,omitempty"`
+	AnchorStartLine *int              `json:"anchor_start_line,omitempty"`
+	AnchorEndLine   *int              `json:"anchor_end_line,omitempty"`
+	Resolved        bool              `json:"resolved"`
+	ResolvedBy      *uint             `json:"resolved_by,omitempty"`
+	ResolvedAt      *time.Time        `json:"resolved_at,omitempty"`
+	Replies         []CommentResponse `json:"replies,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// NotificationResponse is a notification in a user's inbox
+type NotificationResponse struct {
+	ID         uint         `json:"id"`
+	Type       string       `json:"type"`
+	Message    string       `json:"message"`
+	ActorID    uint         `json:"actor_id"`
+	Actor      *UserSummary `json:"actor,omitempty"`
+	DocumentID *uint        `json:"document_id,omitempty"`
+	CommentID  *uint        `json:"comment_id,omitempty"`
+	ReadAt     *time.Time   `json:"read_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// NotificationListResponse is a page of notifications along with the
+// number of unread ones
+type NotificationListResponse struct {
+	*repository.Page[NotificationResponse]
+	Unread int64 `json:"unread"`
+}
+
+// SubscriptionResponse is a user's subscription to a document, service or
+// tag
+type SubscriptionResponse struct {
+	ID         uint      `json:"id"`
+	TargetType string    `json:"target_type"`
+	TargetID   uint      `json:"target_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TagResponse is a tag
+type TagResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TagSummaryResponse is a tag with how many documents the viewer may see
+// carry it
+type TagSummaryResponse struct {
+	TagResponse
+	DocumentCount int64 `json:"document_count"`
+}
+
+// RoleResponse is a role with the names of its permissions
+type RoleResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PermissionResponse is a permission roles can bundle
+type PermissionResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// WebhookResponse is a webhook. Its secret is never shown again after
+// creation.
+type WebhookResponse struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDeliveryResponse is an event queued for a webhook and the outcome
+// of its latest attempt
+type WebhookDeliveryResponse struct {
+	ID             uint       `json:"id"`
+	WebhookID      uint       `json:"webhook_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ServiceResponse is a service of the catalog. DeletedAt is only set for
+// services in the trash.
+type ServiceResponse struct {
+	ID         uint               `json:"id"`
+	Name       string             `json:"name"`
+	Revision   uint               `json:"revision"`
+	TeamID     *uint              `json:"team_id"`
+	Team       *Reference         `json:"team,omitempty"`
+	CategoryID *uint              `json:"category_id"`
+	Category   *CategoryReference `json:"category,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+	DeletedAt  *time.Time         `json:"deleted_at,omitempty"`
+}
+
+// TeamResponse is a team, with its members when they are loaded
+type TeamResponse struct {
+	ID          uint                 `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Members     []TeamMemberResponse `json:"members,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+// TeamMemberResponse is a user's membership of a team
+type TeamMemberResponse struct {
+	TeamID    uint         `json:"team_id"`
+	UserID    uint         `json:"user_id"`
+	User      *UserSummary `json:"user,omitempty"`
+	Role      string       `json:"role"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// SpaceResponse is a space of pages
+type SpaceResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	TeamID      *uint      `json:"team_id"`
+	Team        *Reference `json:"team,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CategoryResponse is a category. RequiredApprovals is 0 when documents in
+// it need the default number of approvals.
+type CategoryResponse struct {
+	ID                uint      `json:"id"`
+	Name              string    `json:"name"`
+	Slug              string    `json:"slug"`
+	Description       string    `json:"description"`
+	Position          int       `json:"position"`
+	RequiredApprovals int       `json:"required_approvals"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// CategorySummaryResponse is a category with how many documents the viewer
+// may see in it and how many services it holds
+type CategorySummaryResponse struct {
+	CategoryResponse
+	DocumentCount int64 `json:"document_count"`
+	ServiceCount  int64 `json:"service_count"`
+}
+
+func newUserResponse(user *model.User) UserResponse {
+	return UserResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      user.Role,
+		Digest:    user.Digest,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
+
+func newDocumentResponse(doc *model.Document) DocumentResponse {
+	resp := DocumentResponse{
+		ID:               doc.ID,
+		Title:            doc.Title,
+		Description:      doc.Description,
+		Content:          doc.Content,
+		Type:             doc.Type,
+		Status:           doc.Status,
+		Tags:             make([]string, len(doc.Tags)),
+		CategoryID:       doc.CategoryID,
+		Category:         newCategoryReference(doc.Category),
+		AuthorID:         doc.AuthorID,
+		Author:           newUserSummary(&doc.Author),
+		ServiceID:        doc.ServiceID,
+		TeamID:           doc.TeamID,
+		SpaceID:          doc.SpaceID,
+		ParentID:         doc.ParentID,
+		Position:         doc.Position,
+		Restricted:       doc.Restricted,
+		Revision:         doc.Revision,
+		PublishedVersion: doc.PublishedVersion,
+		CreatedAt:        doc.CreatedAt,
+		UpdatedAt:        doc.UpdatedAt,
+		DeletedAt:        deletedAt(doc.DeletedAt),
+	}
+	for i, tag := range doc.Tags {
+		resp.Tags[i] = tag.Name
+	}
+	if doc.Service != nil {
+		resp.Service = &Reference{ID: doc.Service.ID, Name: doc.Service.Name}
+	}
+	if doc.Team != nil {
+		resp.Team = &Reference{ID: doc.Team.ID, Name: doc.Team.Name}
+	}
+	if doc.Space != nil {
+		resp.Space = &Reference{ID: doc.Space.ID, Name: doc.Space.Name}
+	}
+	return resp
+}
+
+func newDocumentDetailsResponse(details *service.DocumentDetails) DocumentDetailsResponse {
+	return DocumentDetailsResponse{
+		DocumentResponse: newDocumentResponse(details.Document),
+		Breadcrumbs:      details.Breadcrumbs,
+		Review:           newReviewStateResponse(details.Review),
+	}
+}
+
+func newDocumentVersionResponse(v *model.DocumentVersion) DocumentVersionResponse {
+	tags := []string(v.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return DocumentVersionResponse{
+		DocumentID:   v.DocumentID,
+		Version:      v.Version,
+		Title:        v.Title,
+		Description:  v.Description,
+		Content:      v.Content,
+		Type:         v.Type,
+		CategoryID:   v.CategoryID,
+		Tags:         tags,
+		ServiceID:    v.ServiceID,
+		CreatedBy:    v.CreatedBy,
+		RestoredFrom: v.RestoredFrom,
+		CreatedAt:    v.CreatedAt,
+	}
+}
+
+func newDocumentPermissionResponse(p *model.DocumentPermission) DocumentPermissionResponse {
+	resp := DocumentPermissionResponse{
+		DocumentID: p.DocumentID,
+		UserID:     p.UserID,
+		TeamID:     p.TeamID,
+		Level:      p.Level,
+		GrantedBy:  p.GrantedBy,
+		CreatedAt:  p.CreatedAt,
+		UpdatedAt:  p.UpdatedAt,
+	}
+	if p.User != nil {
+		resp.User = newUserSummary(p.User)
+	}
+	if p.Team != nil {
+		resp.Team = &Reference{ID: p.Team.ID, Name: p.Team.Name}
+	}
+	return resp
+}
+
+func newReviewerResponse(r *model.DocumentReviewer) ReviewerResponse {
+	return ReviewerResponse{
+		DocumentID: r.DocumentID,
+		UserID:     r.UserID,
+		User:       newUserSummary(&r.User),
+		Decision:   r.Decision,
+		Comment:    r.Comment,
+		DecidedAt:  r.DecidedAt,
+	}
+}
+
+func newReviewStateResponse(state *service.ReviewState) *ReviewStateResponse {
+	if state == nil {
+		return nil
+	}
+	return &ReviewStateResponse{
+		RequiredApprovals: state.RequiredApprovals,
+		Approvals:         state.Approvals,
+		Reviewers:         mapSlice(state.Reviewers, newReviewerResponse),
+	}
+}
+
+func newCommentResponse(comment *model.Comment) CommentResponse {
+	resp := CommentResponse{
+		ID:              comment.ID,
+		DocumentID:      comment.DocumentID,
+		UserID:          comment.UserID,
+		User:            newUserSummary(&comment.User),
+		ParentID:        comment.ParentID,
+		Content:         comment.Content,
+		AnchorHeading:   comment.AnchorHeading,
+		AnchorStartLine: comment.AnchorStartLine,
+		AnchorEndLine:   comment.AnchorEndLine,
+		Resolved:        comment.Resolved,
+		ResolvedBy:      comment.ResolvedBy,
+		ResolvedAt:      comment.ResolvedAt,
+		CreatedAt:       comment.CreatedAt,
+		UpdatedAt:       comment.UpdatedAt,
+	}
+	if len(comment.Replies) > 0 {
+		resp.Replies = mapSlice(comment.Replies, newCommentResponse)
+	}
+	return resp
+}
+
+func newNotificationResponse(n *model.Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:         n.ID,
+		Type:       n.Type,
+		Message:    n.Message,
+		ActorID:    n.ActorID,
+		Actor:      newUserSummary(&n.Actor),
+		DocumentID: n.DocumentID,
+		CommentID:  n.CommentID,
+		ReadAt:     n.ReadAt,
+		CreatedAt:  n.CreatedAt,
+	}
+}
+
+func newNotificationListResponse(list *service.NotificationList) NotificationListResponse {
+	return NotificationListResponse{
+		Page:   mapPage(list.Page, newNotificationResponse),
+		Unread: list.Unread,
+	}
+}
+
+func newSubscriptionResponse(sub *model.Subscription) SubscriptionResponse {
+	return SubscriptionResponse{
+		ID:         sub.ID,
+		TargetType: sub.TargetType,
+		TargetID:   sub.TargetID,
+		CreatedAt:  sub.CreatedAt,
+	}
+}
+
+func newTagResponse(tag *model.Tag) TagResponse {
+	return TagResponse{ID: tag.ID, Name: tag.Name, CreatedAt: tag.CreatedAt}
+}
+
+func newTagSummaryResponse(summary *service.TagSummary) TagSummaryResponse {
+	return TagSummaryResponse{
+		TagResponse:   newTagResponse(&summary.Tag),
+		DocumentCount: summary.DocumentCount,
+	}
+}
+
+func newRoleResponse(role *model.Role) RoleResponse {
+	resp := RoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: make([]string, len(role.Permissions)),
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+	for i, permission := range role.Permissions {
+		resp.Permissions[i] = permission.Name
+	}
+	return resp
+}
+
+func newPermissionResponse(permission *model.Permission) PermissionResponse {
+	return PermissionResponse{Name: permission.Name, Description: permission.Description}
+}
+
+func newWebhookResponse(hook *model.Webhook) WebhookResponse {
+	events := []string(hook.Events)
+	if events == nil {
+		events = []string{}
+	}
+	return WebhookResponse{
+		ID:        hook.ID,
+		URL:       hook.URL,
+		Events:    events,
+		Active:    hook.Active,
+		CreatedBy: hook.CreatedBy,
+		CreatedAt: hook.CreatedAt,
+		UpdatedAt: hook.UpdatedAt,
+	}
+}
+
+func newWebhookDeliveryResponse(delivery *model.WebhookDelivery) WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		Event:          delivery.Event,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		ResponseStatus: delivery.ResponseStatus,
+		Error:          delivery.Error,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+}
+
+func newServiceResponse(svc *model.Service) ServiceResponse {
+	resp := ServiceResponse{
+		ID:         svc.ID,
+		Name:       svc.Name,
+		Revision:   svc.Revision,
+		TeamID:     svc.TeamID,
+		CategoryID: svc.CategoryID,
+		Category:   newCategoryReference(svc.Category),
+		CreatedAt:  svc.CreatedAt,
+		UpdatedAt:  svc.UpdatedAt,
+		DeletedAt:  deletedAt(svc.DeletedAt),
+	}
+	if svc.Team != nil {
+		resp.Team = &Reference{ID: svc.Team.ID, Name: svc.Team.Name}
+	}
+	return resp
+}
+
+func newTeamResponse(team *model.Team) TeamResponse {
+	resp := TeamResponse{
+		ID:          team.ID,
+		Name:        team.Name,
+		Description: team.Description,
+		CreatedAt:   team.CreatedAt,
+		UpdatedAt:   team.UpdatedAt,
+	}
+	for i := range team.Members {
+		resp.Members = append(resp.Members, newTeamMemberResponse(&team.Members[i]))
+	}
+	return resp
+}
+
+func newTeamMemberResponse(member *model.TeamMember) TeamMemberResponse {
+	return TeamMemberResponse{
+		TeamID:    member.TeamID,
+		UserID:    member.UserID,
+		User:      newUserSummary(&member.User),
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt,
+		UpdatedAt: member.UpdatedAt,
+	}
+}
+
+func newSpaceResponse(space *model.Space) SpaceResponse {
+	resp := SpaceResponse{
+		ID:          space.ID,
+		Name:        space.Name,
+		Description: space.Description,
+		TeamID:      space.TeamID,
+		CreatedAt:   space.CreatedAt,
+		UpdatedAt:   space.UpdatedAt,
+	}
+	if space.Team != nil {
+		resp.Team = &Reference{ID: space.Team.ID, Name: space.Team.Name}
+	}
+	return resp
+}
+
+func newCategoryResponse(category *model.Category) CategoryResponse {
+	return CategoryResponse{
+		ID:                category.ID,
+		Name:              category.Name,
+		Slug:              category.Slug,
+		Description:       category.Description,
+		Position:          category.Position,
+		RequiredApprovals: category.RequiredApprovals,
+		CreatedAt:         category.CreatedAt,
+		UpdatedAt:         category.UpdatedAt,
+	}
+}
+
+func newCategorySummaryResponse(summary *service.CategorySummary) CategorySummaryResponse {
+	return CategorySummaryResponse{
+		CategoryResponse: newCategoryResponse(&summary.Category),
+		DocumentCount:    summary.DocumentCount,
+		ServiceCount:     summary.ServiceCount,
+	}
+}
+
+// newUserSummary identifies a loaded user, or returns nil when the user
+// wasn't loaded
+func newUserSummary(user *model.User) *UserSummary {
+	if user == nil || user.ID == 0 {
+		return nil
+	}
+	return &UserSummary{ID: user.ID, Username: user.Username}
+}
+
+func newCategoryReference(category *model.Category) *CategoryReference {
+	if category == nil {
+		return nil
+	}
+	return &CategoryReference{ID: category.ID, Name: category.Name, Slug: category.Slug}
+}
+
+func deletedAt(deleted gorm.DeletedAt) *time.Time {
+	if !deleted.Valid {
+		return nil
+	}
+	return &deleted.Time
+}
+
+// mapSlice turns a list of models into a list of responses
+func mapSlice[T, R any](items []T, respond func(*T) R) []R {
+	result := make([]R, len(items))
+	for i := range items {
+		result[i] = respond(&items[i])
+	}
+	return result
+}
+
+// mapPage turns a page of models into a page of responses
+func mapPage[T, R any](page *repository.Page[T], respond func(*T) R) *repository.Page[R] {
+	return &repository.Page[R]{
+		Items:      mapSlice(page.Items, respond),
+		Total:      page.Total,
+		Page:       page.Page,
+		Limit:      page.Limit,
+		NextCursor: page.NextCursor,
+	}
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"techdocs/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag formats a revision as a strong entity tag
+func etag(revision uint) string {
+	return fmt.Sprintf("\"%d\"", revision)
+}
+
+// expectedRevision returns the revision an update is based on, taken from
+// the If-Match header or, when the header is absent, from the request body
+func expectedRevision(c *gin.Context, bodyRevision uint) (uint, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return bodyRevision, bodyRevision != 0
+	}
+
+	tag := strings.TrimPrefix(strings.TrimSpace(header), "W/")
+	revision, err := strconv.ParseUint(strings.Trim(tag, "\""), 10, 32)
+	if err != nil || revision == 0 {
+		return 0, false
+	}
+	return uint(revision), true
+}
+
+// failUpdate hands the error of an update to middleware.Errors. A revision
+// conflict carries the current state of the resource, as given by current,
+// so the client can merge its changes.
+func failUpdate(c *gin.Context, err error, current func() any) {
+	if errors.Is(err, repository.ErrRevisionConflict) {
+		_ = c.Error(err).SetMeta(gin.H{"current": current()})
+		return
+	}
+	fail(c, err)
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"techdocs/internal/middleware"
+	"techdocs/internal/model"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleHandler struct {
+	roleService *service.RoleService
+}
+
+func NewRoleHandler(roleService *service.RoleService) *RoleHandler {
+	return &RoleHandler{
+		roleService: roleService,
+	}
+}
+
+// RegisterRoutes registers the role administration routes
+func (h *RoleHandler) RegisterRoutes(router *gin.RouterGroup) {
+	admin := router.Group("/admin")
+	admin.Use(middleware.RequirePermission(model.PermUsersAdmin))
+	{
+		admin.GET("/roles", h.GetAllRoles)
+		admin.POST("/roles", h.CreateRole)
+		admin.PUT("/roles/:id", h.UpdateRole)
+		admin.DELETE("/roles/:id", h.DeleteRole)
+		admin.GET("/permissions", h.GetAllPermissions)
+		admin.PUT("/users/:id/role", h.AssignRole)
+	}
+}
+
+// RoleRequest is the body for creating or updating a role
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// AssignRoleRequest is the body for assigning a role to a user
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// GetAllRoles handles the retrieval of all roles
+func (h *RoleHandler) GetAllRoles(c *gin.Context) {
+	roles, err := h.roleService.GetAllRoles()
+	if err != nil {
+		logger.Error("Failed to get all roles: %v", err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapSlice(roles, newRoleResponse))
+}
+
+// CreateRole handles the creation of a new role
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Role creation validation error: %v", err)
+		failBinding(c, err)
+		return
+	}
+	if req.Name == "" {
+		badRequest(c, "invalid_request", "Role name is required")
+		return
+	}
+
+	role, err := h.roleService.CreateRole(req.Name, req.Description, req.Permissions)
+	if err != nil {
+		logger.Error("Failed to create role %s: %v", req.Name, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Role created successfully: %s by user ID %d", role.Name, c.GetUint("userID"))
+	c.JSON(http.StatusCreated, newRoleResponse(role))
+}
+
+// UpdateRole handles changing a role's description and permissions
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid role ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid role ID format")
+		return
+	}
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Role update validation error for ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(uint(id), req.Description, req.Permissions)
+	if err != nil {
+		logger.Error("Failed to update role ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Role updated successfully: %s by user ID %d", role.Name, c.GetUint("userID"))
+	c.JSON(http.StatusOK, newRoleResponse(role))
+}
+
+// DeleteRole handles the deletion of a role
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid role ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid role ID format")
+		return
+	}
+
+	if err := h.roleService.DeleteRole(uint(id)); err != nil {
+		logger.Error("Failed to delete role ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Role deleted successfully: ID %d by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// GetAllPermissions handles the retrieval of all known permissions
+func (h *RoleHandler) GetAllPermissions(c *gin.Context) {
+	permissions, err := h.roleService.GetAllPermissions()
+	if err != nil {
+		logger.Error("Failed to get all permissions: %v", err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapSlice(permissions, newPermissionResponse))
+}
+
+// AssignRole handles giving a user a role
+func (h *RoleHandler) AssignRole(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid user ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid user ID format")
+		return
+	}
+
+	var req AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Role assignment validation error for user ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+
+	if err := h.roleService.AssignRole(uint(id), req.Role); err != nil {
+		logger.Error("Failed to assign role %s to user ID %d: %v", req.Role, id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Role %s assigned to user ID %d by user ID %d", req.Role, id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, gin.H{"message": "Role assigned successfully"})
+}
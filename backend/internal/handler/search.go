@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search result limits
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type SearchHandler struct {
+	searchService *service.SearchService
+}
+
+func NewSearchHandler(searchService *service.SearchService) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+	}
+}
+
+// RegisterRoutes registers the search routes
+func (h *SearchHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/search", h.Search)
+}
+
+// Search handles full-text search across documents, services and comments.
+// The optional type parameter takes a comma separated list of document,
+// service and comment.
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := c.Query("q")
+
+	limit := defaultSearchLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 {
+			logger.Error("Invalid search limit: %s", limitStr)
+			badRequest(c, "invalid_parameter", "Invalid limit")
+			return
+		}
+		limit = n
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	var types []string
+	if typeStr := c.Query("type"); typeStr != "" {
+		for _, t := range strings.Split(typeStr, ",") {
+			t = strings.TrimSpace(t)
+			if t != service.SearchTypeDocument && t != service.SearchTypeService && t != service.SearchTypeComment {
+				logger.Error("Invalid search type: %s", t)
+				badRequest(c, "invalid_parameter", "Invalid type, use document, service or comment")
+				return
+			}
+			types = append(types, t)
+		}
+	}
+
+	resp, err := h.searchService.Search(query, types, limit, actorFrom(c))
+	if err != nil {
+		logger.Error("Search failed for query %q: %v", query, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
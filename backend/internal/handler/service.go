@@ -3,6 +3,8 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"techdocs/internal/middleware"
 	"techdocs/internal/model"
 	"techdocs/internal/service"
 	"techdocs/pkg/logger"
@@ -10,6 +12,39 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// ServiceRequest is the body for creating or updating a service
+type ServiceRequest struct {
+	Name       string `json:"name" binding:"required,max=255"`
+	TeamID     *uint  `json:"team_id"`
+	CategoryID *uint  `json:"category_id"`
+	Revision   uint   `json:"revision"`
+}
+
+// toModel turns the request into the service it describes
+func (r *ServiceRequest) toModel() *model.Service {
+	return &model.Service{
+		Name:       r.Name,
+		TeamID:     r.TeamID,
+		CategoryID: r.CategoryID,
+		Revision:   r.Revision,
+	}
+}
+
+// newServiceRequest returns the request that would update a service to its
+// current state
+func newServiceRequest(svc *model.Service) ServiceRequest {
+	return ServiceRequest{
+		Name:       svc.Name,
+		TeamID:     svc.TeamID,
+		CategoryID: svc.CategoryID,
+		Revision:   svc.Revision,
+	}
+}
+
+// patchableServiceFields are the members of a ServiceRequest a PATCH may
+// change
+var patchableServiceFields = []string{"name", "team_id", "category_id"}
+
 type ServiceHandler struct {
 	serviceService *service.ServiceService
 }
@@ -22,11 +57,14 @@ func NewServiceHandler(serviceService *service.ServiceService) *ServiceHandler {
 
 // RegisterRoutes registers the service routes
 func (h *ServiceHandler) RegisterRoutes(router *gin.RouterGroup) {
+	canManage := middleware.RequirePermission(model.PermServicesManage)
+
 	services := router.Group("/services")
 	{
-		services.POST("", h.CreateService)
-		services.PUT("/:id", h.UpdateService)
-		services.DELETE("/:id", h.DeleteService)
+		services.POST("", canManage, h.CreateService)
+		services.PUT("/:id", canManage, h.UpdateService)
+		services.PATCH("/:id", canManage, h.PatchService)
+		services.DELETE("/:id", canManage, h.DeleteService)
 		services.GET("/:id", h.GetServiceByID)
 		services.GET("", h.GetAllServices)
 		services.GET("/category/:category", h.GetServicesByCategory)
@@ -35,21 +73,22 @@ func (h *ServiceHandler) RegisterRoutes(router *gin.RouterGroup) {
 
 // CreateService handles the creation of a new service
 func (h *ServiceHandler) CreateService(c *gin.Context) {
-	var svc model.Service
-	if err := c.ShouldBindJSON(&svc); err != nil {
+	var req ServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Service creation validation error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		failBinding(c, err)
 		return
 	}
+	svc := req.toModel()
 
-	if err := h.serviceService.CreateService(&svc); err != nil {
+	if err := h.serviceService.CreateService(svc, actorFrom(c)); err != nil {
 		logger.Error("Failed to create service: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
 	logger.Info("Service created successfully: ID %d", svc.ID)
-	c.JSON(http.StatusCreated, svc)
+	c.JSON(http.StatusCreated, newServiceResponse(svc))
 }
 
 // UpdateService handles the update of an existing service
@@ -58,27 +97,97 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
 		logger.Error("Invalid service ID format: %s", idStr)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID format"})
+		badRequest(c, "invalid_id", "Invalid service ID format")
 		return
 	}
 
-	var svc model.Service
-	if err := c.ShouldBindJSON(&svc); err != nil {
+	var req ServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Service update validation error for ID %d: %v", id, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		failBinding(c, err)
+		return
+	}
+	svc := req.toModel()
+
+	revision, ok := expectedRevision(c, svc.Revision)
+	if !ok {
+		logger.Error("Service update for ID %d without revision", id)
+		middleware.AbortWithProblem(c, http.StatusPreconditionRequired, "revision_required", "If-Match header or revision field is required")
 		return
 	}
 
 	svc.ID = uint(id)
+	svc.Revision = revision
 
-	if err := h.serviceService.UpdateService(&svc); err != nil {
+	if err := h.serviceService.UpdateService(svc, actorFrom(c)); err != nil {
 		logger.Error("Failed to update service ID %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		failUpdate(c, err, h.currentService(uint(id)))
 		return
 	}
 
 	logger.Info("Service updated successfully: ID %d", id)
-	c.JSON(http.StatusOK, svc)
+	c.Header("ETag", etag(svc.Revision))
+	c.JSON(http.StatusOK, newServiceResponse(svc))
+}
+
+// PatchService handles a partial update of a service, given as a JSON
+// Merge Patch or a JSON Patch of its ServiceRequest. The revision the
+// patch is based on is given by If-Match or by setting the revision member.
+func (h *ServiceHandler) PatchService(c *gin.Context) {
+	id, ok := parseServiceID(c)
+	if !ok {
+		return
+	}
+
+	existing, err := h.serviceService.GetServiceByID(id)
+	if err != nil {
+		logger.Error("Failed to get service ID %d for patch: %v", id, err)
+		fail(c, err)
+		return
+	}
+	current := newServiceRequest(existing)
+	current.Revision = 0
+
+	var req ServiceRequest
+	changed, ok := patchRequest(c, current, &req, append(patchableServiceFields, revisionField)...)
+	if !ok {
+		return
+	}
+	revision, ok := patchRevision(c, req.Revision)
+	if !ok {
+		return
+	}
+	if len(changed) == 0 {
+		c.Header("ETag", etag(existing.Revision))
+		c.JSON(http.StatusOK, newServiceResponse(existing))
+		return
+	}
+
+	svc := req.toModel()
+	svc.ID = id
+	svc.Revision = revision
+
+	if err := h.serviceService.UpdateService(svc, actorFrom(c)); err != nil {
+		logger.Error("Failed to patch service ID %d: %v", id, err)
+		failUpdate(c, err, h.currentService(id))
+		return
+	}
+
+	logger.Info("Service patched successfully: ID %d, changed %s", id, strings.Join(changed, ", "))
+	c.Header("ETag", etag(svc.Revision))
+	c.JSON(http.StatusOK, newServiceResponse(svc))
+}
+
+// currentService returns a function looking up the current state of a
+// service for a revision conflict
+func (h *ServiceHandler) currentService(id uint) func() any {
+	return func() any {
+		existing, _ := h.serviceService.GetServiceByID(id)
+		if existing == nil {
+			return nil
+		}
+		return newServiceResponse(existing)
+	}
 }
 
 // DeleteService handles the deletion of a service
@@ -87,13 +196,13 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
 		logger.Error("Invalid service ID format: %s", idStr)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID format"})
+		badRequest(c, "invalid_id", "Invalid service ID format")
 		return
 	}
 
-	if err := h.serviceService.DeleteService(uint(id)); err != nil {
+	if err := h.serviceService.DeleteService(uint(id), actorFrom(c)); err != nil {
 		logger.Error("Failed to delete service ID %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
@@ -107,41 +216,54 @@ func (h *ServiceHandler) GetServiceByID(c *gin.Context) {
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
 		logger.Error("Invalid service ID format: %s", idStr)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID format"})
+		badRequest(c, "invalid_id", "Invalid service ID format")
 		return
 	}
 
 	svc, err := h.serviceService.GetServiceByID(uint(id))
 	if err != nil {
 		logger.Error("Failed to get service ID %d: %v", id, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, svc)
+	c.Header("ETag", etag(svc.Revision))
+	c.JSON(http.StatusOK, newServiceResponse(svc))
 }
 
-// GetAllServices handles the retrieval of all services
+// GetAllServices handles the retrieval of a page of services
 func (h *ServiceHandler) GetAllServices(c *gin.Context) {
-	services, err := h.serviceService.GetAllServices()
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.serviceService.GetAllServices(q)
 	if err != nil {
 		logger.Error("Failed to get all services: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, services)
+	c.JSON(http.StatusOK, mapPage(page, newServiceResponse))
 }
 
-// GetServicesByCategory handles the retrieval of services by category
+// GetServicesByCategory handles the retrieval of a page of services by
+// category, given by slug or name
 func (h *ServiceHandler) GetServicesByCategory(c *gin.Context) {
 	category := c.Param("category")
-	services, err := h.serviceService.GetServicesByCategory(category)
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+	q.Filter.Category = category
+
+	page, err := h.serviceService.GetAllServices(q)
 	if err != nil {
 		logger.Error("Failed to get services for category %s: %v", category, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, services)
+	c.JSON(http.StatusOK, mapPage(page, newServiceResponse))
 }
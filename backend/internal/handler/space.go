@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"techdocs/internal/middleware"
+	"techdocs/internal/model"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MoveRequest is the body for moving a document. With only a parent the
+// document joins the parent's space; with neither it leaves its space. A
+// missing position puts it after its new siblings.
+type MoveRequest struct {
+	SpaceID  *uint `json:"space_id"`
+	ParentID *uint `json:"parent_id"`
+	Position *int  `json:"position" binding:"omitempty,min=0"`
+}
+
+// SpaceRequest is the body for creating or updating a space
+type SpaceRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description" binding:"max=1000"`
+	TeamID      *uint  `json:"team_id"`
+}
+
+// toModel turns the request into the space it describes
+func (r *SpaceRequest) toModel() *model.Space {
+	return &model.Space{
+		Name:        r.Name,
+		Description: r.Description,
+		TeamID:      r.TeamID,
+	}
+}
+
+type SpaceHandler struct {
+	spaceService *service.SpaceService
+}
+
+func NewSpaceHandler(spaceService *service.SpaceService) *SpaceHandler {
+	return &SpaceHandler{
+		spaceService: spaceService,
+	}
+}
+
+// RegisterRoutes registers the space routes
+func (h *SpaceHandler) RegisterRoutes(router *gin.RouterGroup) {
+	canManage := middleware.RequirePermission(model.PermSpacesManage)
+
+	spaces := router.Group("/spaces")
+	{
+		spaces.POST("", canManage, h.CreateSpace)
+		spaces.PUT("/:id", canManage, h.UpdateSpace)
+		spaces.DELETE("/:id", canManage, h.DeleteSpace)
+		spaces.GET("/:id", h.GetSpaceByID)
+		spaces.GET("/:id/tree", h.GetSpaceTree)
+		spaces.GET("", h.GetAllSpaces)
+	}
+}
+
+// CreateSpace handles the creation of a new space
+func (h *SpaceHandler) CreateSpace(c *gin.Context) {
+	var req SpaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Space creation validation error: %v", err)
+		failBinding(c, err)
+		return
+	}
+	space := req.toModel()
+
+	if err := h.spaceService.CreateSpace(space, actorFrom(c)); err != nil {
+		logger.Error("Failed to create space %s: %v", space.Name, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Space created successfully: ID %d by user ID %d", space.ID, c.GetUint("userID"))
+	c.JSON(http.StatusCreated, newSpaceResponse(space))
+}
+
+// UpdateSpace handles the update of an existing space
+func (h *SpaceHandler) UpdateSpace(c *gin.Context) {
+	id, ok := parseSpaceID(c)
+	if !ok {
+		return
+	}
+
+	var req SpaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Space update validation error for ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+	space := req.toModel()
+	space.ID = id
+
+	if err := h.spaceService.UpdateSpace(space, actorFrom(c)); err != nil {
+		logger.Error("Failed to update space ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Space updated successfully: ID %d by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, newSpaceResponse(space))
+}
+
+// DeleteSpace handles the deletion of a space
+func (h *SpaceHandler) DeleteSpace(c *gin.Context) {
+	id, ok := parseSpaceID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.spaceService.DeleteSpace(id); err != nil {
+		logger.Error("Failed to delete space ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Space deleted successfully: ID %d by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, gin.H{"message": "Space deleted successfully"})
+}
+
+// GetSpaceByID handles the retrieval of a space by its ID
+func (h *SpaceHandler) GetSpaceByID(c *gin.Context) {
+	id, ok := parseSpaceID(c)
+	if !ok {
+		return
+	}
+
+	space, err := h.spaceService.GetSpaceByID(id)
+	if err != nil {
+		logger.Error("Failed to get space ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newSpaceResponse(space))
+}
+
+// GetSpaceTree handles the retrieval of the page tree of a space
+func (h *SpaceHandler) GetSpaceTree(c *gin.Context) {
+	id, ok := parseSpaceID(c)
+	if !ok {
+		return
+	}
+
+	tree, err := h.spaceService.GetTree(id, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to get tree of space ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tree)
+}
+
+// GetAllSpaces handles the retrieval of a page of spaces
+func (h *SpaceHandler) GetAllSpaces(c *gin.Context) {
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.spaceService.GetAllSpaces(q)
+	if err != nil {
+		logger.Error("Failed to get spaces: %v", err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newSpaceResponse))
+}
+
+// MoveDocument handles moving a document to another place in the page
+// trees, or to another position among its siblings
+func (h *DocumentHandler) MoveDocument(c *gin.Context) {
+	id, ok := parseDocumentID(c)
+	if !ok {
+		return
+	}
+
+	var req MoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Move validation error for document ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+
+	doc, err := h.documentService.MoveDocument(id, req.SpaceID, req.ParentID, req.Position, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to move document ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Document ID %d moved by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, newDocumentResponse(doc))
+}
+
+func parseSpaceID(c *gin.Context) (uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid space ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid space ID format")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubscribeRequest is the body for subscribing to a document, service or
+// tag
+type SubscribeRequest struct {
+	TargetType string `json:"target_type" binding:"required,oneof=document service tag"`
+	TargetID   uint   `json:"target_id" binding:"required"`
+}
+
+type SubscriptionHandler struct {
+	subscriptionService *service.SubscriptionService
+}
+
+func NewSubscriptionHandler(subscriptionService *service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		subscriptionService: subscriptionService,
+	}
+}
+
+// RegisterRoutes registers the subscription routes
+func (h *SubscriptionHandler) RegisterRoutes(router *gin.RouterGroup) {
+	subscriptions := router.Group("/subscriptions")
+	{
+		subscriptions.GET("", h.GetSubscriptions)
+		subscriptions.POST("", h.Subscribe)
+		subscriptions.DELETE("/:id", h.Unsubscribe)
+	}
+}
+
+// GetSubscriptions handles the retrieval of a page of the current user's
+// subscriptions. The type parameter narrows them to one target type.
+func (h *SubscriptionHandler) GetSubscriptions(c *gin.Context) {
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	actor := actorFrom(c)
+	page, err := h.subscriptionService.GetSubscriptions(actor, q)
+	if err != nil {
+		logger.Error("Failed to get subscriptions for user ID %d: %v", actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newSubscriptionResponse))
+}
+
+// Subscribe handles subscribing the current user to a document, service or
+// tag
+func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
+	var req SubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Subscription validation error: %v", err)
+		failBinding(c, err)
+		return
+	}
+
+	actor := actorFrom(c)
+	subscription, err := h.subscriptionService.Subscribe(req.TargetType, req.TargetID, actor)
+	if err != nil {
+		logger.Error("Failed to subscribe user ID %d to %s ID %d: %v", actor.UserID, req.TargetType, req.TargetID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("User ID %d subscribed to %s ID %d", actor.UserID, req.TargetType, req.TargetID)
+	c.JSON(http.StatusCreated, newSubscriptionResponse(subscription))
+}
+
+// Unsubscribe handles deleting one of the current user's subscriptions
+func (h *SubscriptionHandler) Unsubscribe(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid subscription ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid subscription ID format")
+		return
+	}
+
+	actor := actorFrom(c)
+	if err := h.subscriptionService.Unsubscribe(uint(id), actor); err != nil {
+		logger.Error("Failed to delete subscription ID %d for user ID %d: %v", id, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Subscription deleted successfully: ID %d by user ID %d", id, actor.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription deleted successfully"})
+}
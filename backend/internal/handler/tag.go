@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"net/http"
+	"techdocs/internal/middleware"
+	"techdocs/internal/model"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RenameTagRequest is the body for renaming a tag
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// MergeTagRequest is the body for merging a tag into the tag named Into
+type MergeTagRequest struct {
+	Into string `json:"into" binding:"required"`
+}
+
+type TagHandler struct {
+	tagService *service.TagService
+}
+
+func NewTagHandler(tagService *service.TagService) *TagHandler {
+	return &TagHandler{
+		tagService: tagService,
+	}
+}
+
+// RegisterRoutes registers the tag routes. Tags are named in paths.
+func (h *TagHandler) RegisterRoutes(router *gin.RouterGroup) {
+	canManage := middleware.RequirePermission(model.PermTagsManage)
+
+	tags := router.Group("/tags")
+	{
+		tags.GET("", h.GetAllTags)
+		tags.GET("/:name/documents", h.GetTagDocuments)
+		tags.PUT("/:name", canManage, h.RenameTag)
+		tags.POST("/:name/merge", canManage, h.MergeTag)
+	}
+}
+
+// GetAllTags handles the retrieval of a page of tags with their document
+// counts. The prefix query parameter serves autocompletion.
+func (h *TagHandler) GetAllTags(c *gin.Context) {
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.tagService.GetAllTags(c.Query("prefix"), actorFrom(c), q)
+	if err != nil {
+		logger.Error("Failed to get tags: %v", err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newTagSummaryResponse))
+}
+
+// GetTagDocuments handles the retrieval of a page of the documents
+// carrying a tag. The document list filters apply.
+func (h *TagHandler) GetTagDocuments(c *gin.Context) {
+	name := c.Param("name")
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.tagService.GetTagDocuments(name, actorFrom(c), q)
+	if err != nil {
+		logger.Error("Failed to get documents for tag %s: %v", name, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newDocumentResponse))
+}
+
+// RenameTag handles renaming a tag
+func (h *TagHandler) RenameTag(c *gin.Context) {
+	name := c.Param("name")
+
+	var req RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Tag rename validation error for %s: %v", name, err)
+		failBinding(c, err)
+		return
+	}
+
+	tag, err := h.tagService.RenameTag(name, req.Name)
+	if err != nil {
+		logger.Error("Failed to rename tag %s: %v", name, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Tag %s renamed to %s by user ID %d", name, tag.Name, c.GetUint("userID"))
+	c.JSON(http.StatusOK, newTagResponse(tag))
+}
+
+// MergeTag handles merging a tag into another one
+func (h *TagHandler) MergeTag(c *gin.Context) {
+	name := c.Param("name")
+
+	var req MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Tag merge validation error for %s: %v", name, err)
+		failBinding(c, err)
+		return
+	}
+
+	tag, err := h.tagService.MergeTags(name, req.Into)
+	if err != nil {
+		logger.Error("Failed to merge tag %s into %s: %v", name, req.Into, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Tag %s merged into %s by user ID %d", name, tag.Name, c.GetUint("userID"))
+	c.JSON(http.StatusOK, newTagResponse(tag))
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"techdocs/internal/middleware"
+	"techdocs/internal/model"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TeamRequest is the body for creating or updating a team
+type TeamRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description" binding:"max=1000"`
+}
+
+// toModel turns the request into the team it describes
+func (r *TeamRequest) toModel() *model.Team {
+	return &model.Team{
+		Name:        r.Name,
+		Description: r.Description,
+	}
+}
+
+type TeamHandler struct {
+	teamService *service.TeamService
+}
+
+func NewTeamHandler(teamService *service.TeamService) *TeamHandler {
+	return &TeamHandler{
+		teamService: teamService,
+	}
+}
+
+// RegisterRoutes registers the team routes. Changing a team is up to its
+// admins, creating one needs a permission.
+func (h *TeamHandler) RegisterRoutes(router *gin.RouterGroup) {
+	canCreate := middleware.RequirePermission(model.PermTeamsCreate)
+
+	teams := router.Group("/teams")
+	{
+		teams.POST("", canCreate, h.CreateTeam)
+		teams.PUT("/:id", h.UpdateTeam)
+		teams.DELETE("/:id", h.DeleteTeam)
+		teams.GET("/:id", h.GetTeamByID)
+		teams.GET("", h.GetAllTeams)
+		teams.GET("/:id/members", h.GetTeamMembers)
+		teams.PUT("/:id/members/:userID", h.SetTeamMember)
+		teams.DELETE("/:id/members/:userID", h.RemoveTeamMember)
+	}
+}
+
+// CreateTeam handles the creation of a new team
+func (h *TeamHandler) CreateTeam(c *gin.Context) {
+	var req TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Team creation validation error: %v", err)
+		failBinding(c, err)
+		return
+	}
+	team := req.toModel()
+
+	actor := actorFrom(c)
+	if err := h.teamService.CreateTeam(team, actor); err != nil {
+		logger.Error("Failed to create team for user ID %d: %v", actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Team created successfully: ID %d by user ID %d", team.ID, actor.UserID)
+	c.JSON(http.StatusCreated, newTeamResponse(team))
+}
+
+// UpdateTeam handles the update of an existing team
+func (h *TeamHandler) UpdateTeam(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid team ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid team ID format")
+		return
+	}
+
+	var req TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Team update validation error for ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+	team := req.toModel()
+	team.ID = uint(id)
+
+	actor := actorFrom(c)
+	if err := h.teamService.UpdateTeam(team, actor); err != nil {
+		logger.Error("Failed to update team ID %d by user ID %d: %v", id, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Team updated successfully: ID %d by user ID %d", id, actor.UserID)
+	c.JSON(http.StatusOK, newTeamResponse(team))
+}
+
+// DeleteTeam handles the deletion of a team
+func (h *TeamHandler) DeleteTeam(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid team ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid team ID format")
+		return
+	}
+
+	actor := actorFrom(c)
+	if err := h.teamService.DeleteTeam(uint(id), actor); err != nil {
+		logger.Error("Failed to delete team ID %d by user ID %d: %v", id, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Team deleted successfully: ID %d by user ID %d", id, actor.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted successfully"})
+}
+
+// GetTeamByID handles the retrieval of a team with its members
+func (h *TeamHandler) GetTeamByID(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid team ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid team ID format")
+		return
+	}
+
+	team, err := h.teamService.GetTeamByID(uint(id))
+	if err != nil {
+		logger.Error("Failed to get team ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newTeamResponse(team))
+}
+
+// GetAllTeams handles the retrieval of a page of teams
+func (h *TeamHandler) GetAllTeams(c *gin.Context) {
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.teamService.GetAllTeams(q)
+	if err != nil {
+		logger.Error("Failed to get all teams: %v", err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newTeamResponse))
+}
+
+// GetTeamMembers handles the retrieval of a team's members
+func (h *TeamHandler) GetTeamMembers(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid team ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid team ID format")
+		return
+	}
+
+	members, err := h.teamService.GetTeamMembers(uint(id))
+	if err != nil {
+		logger.Error("Failed to get members of team ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapSlice(members, newTeamMemberResponse))
+}
+
+// SetTeamMemberRequest is the body of a membership change
+type SetTeamMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=member admin"`
+}
+
+// SetTeamMember handles adding a user to a team or changing their role
+func (h *TeamHandler) SetTeamMember(c *gin.Context) {
+	id, userID, ok := parseTeamAndUserID(c)
+	if !ok {
+		return
+	}
+
+	var req SetTeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Team member validation error for team ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+
+	actor := actorFrom(c)
+	member, err := h.teamService.SetTeamMember(id, userID, req.Role, actor)
+	if err != nil {
+		logger.Error("Failed to set user ID %d as %s of team ID %d by user ID %d: %v", userID, req.Role, id, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("User ID %d set as %s of team ID %d by user ID %d", userID, req.Role, id, actor.UserID)
+	c.JSON(http.StatusOK, newTeamMemberResponse(member))
+}
+
+// RemoveTeamMember handles removing a user from a team
+func (h *TeamHandler) RemoveTeamMember(c *gin.Context) {
+	id, userID, ok := parseTeamAndUserID(c)
+	if !ok {
+		return
+	}
+
+	actor := actorFrom(c)
+	if err := h.teamService.RemoveTeamMember(id, userID, actor); err != nil {
+		logger.Error("Failed to remove user ID %d from team ID %d by user ID %d: %v", userID, id, actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("User ID %d removed from team ID %d by user ID %d", userID, id, actor.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Team member removed successfully"})
+}
+
+// parseTeamAndUserID parses the id and userID path parameters, writing a
+// 400 response when either is malformed
+func parseTeamAndUserID(c *gin.Context) (uint, uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid team ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid team ID format")
+		return 0, 0, false
+	}
+
+	userIDStr := c.Param("userID")
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid user ID format: %s", userIDStr)
+		badRequest(c, "invalid_id", "Invalid user ID format")
+		return 0, 0, false
+	}
+
+	return uint(id), uint(userID), true
+}
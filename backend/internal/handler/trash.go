@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"techdocs/internal/middleware"
+	"techdocs/internal/model"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrashHandler struct {
+	trashService *service.TrashService
+}
+
+func NewTrashHandler(trashService *service.TrashService) *TrashHandler {
+	return &TrashHandler{
+		trashService: trashService,
+	}
+}
+
+// RegisterRoutes registers the trash routes. Deleting from the trash is
+// permanent and reserved to admins.
+func (h *TrashHandler) RegisterRoutes(router *gin.RouterGroup) {
+	canManageServices := middleware.RequirePermission(model.PermServicesManage)
+	canPurge := middleware.RequirePermission(model.PermTrashPurge)
+
+	trash := router.Group("/trash")
+	{
+		trash.GET("", h.GetDeletedDocuments)
+		trash.GET("/services", h.GetDeletedServices)
+		trash.POST("/documents/:id/restore", h.RestoreDocument)
+		trash.POST("/services/:id/restore", canManageServices, h.RestoreService)
+		trash.DELETE("/documents/:id", canPurge, h.PurgeDocument)
+		trash.DELETE("/services/:id", canPurge, h.PurgeService)
+	}
+}
+
+// GetDeletedDocuments handles the retrieval of a page of the deleted
+// documents the current user may restore, most recently deleted first.
+// The document list filters apply.
+func (h *TrashHandler) GetDeletedDocuments(c *gin.Context) {
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.trashService.GetDeletedDocuments(actorFrom(c), q)
+	if err != nil {
+		logger.Error("Failed to get deleted documents: %v", err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newDocumentResponse))
+}
+
+// GetDeletedServices handles the retrieval of a page of deleted services,
+// most recently deleted first
+func (h *TrashHandler) GetDeletedServices(c *gin.Context) {
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.trashService.GetDeletedServices(q)
+	if err != nil {
+		logger.Error("Failed to get deleted services: %v", err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newServiceResponse))
+}
+
+// RestoreDocument handles bringing a deleted document back
+func (h *TrashHandler) RestoreDocument(c *gin.Context) {
+	id, ok := parseDocumentID(c)
+	if !ok {
+		return
+	}
+
+	doc, err := h.trashService.RestoreDocument(id, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to restore deleted document ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Deleted document ID %d restored by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, newDocumentResponse(doc))
+}
+
+// RestoreService handles bringing a deleted service back
+func (h *TrashHandler) RestoreService(c *gin.Context) {
+	id, ok := parseServiceID(c)
+	if !ok {
+		return
+	}
+
+	svc, err := h.trashService.RestoreService(id, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to restore deleted service ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Deleted service ID %d restored by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, newServiceResponse(svc))
+}
+
+// PurgeDocument handles removing a deleted document for good
+func (h *TrashHandler) PurgeDocument(c *gin.Context) {
+	id, ok := parseDocumentID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.trashService.PurgeDocument(id); err != nil {
+		logger.Error("Failed to purge document ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Document ID %d purged from the trash by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, gin.H{"message": "Document permanently deleted"})
+}
+
+// PurgeService handles removing a deleted service for good
+func (h *TrashHandler) PurgeService(c *gin.Context) {
+	id, ok := parseServiceID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.trashService.PurgeService(id); err != nil {
+		logger.Error("Failed to purge service ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Service ID %d purged from the trash by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, gin.H{"message": "Service permanently deleted"})
+}
+
+func parseServiceID(c *gin.Context) (uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid service ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid service ID format")
+		return 0, false
+	}
+	return uint(id), true
+}
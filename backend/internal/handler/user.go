@@ -10,13 +10,36 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// UpdateProfileRequest is the body for updating one's own profile. Empty
+// fields are left unchanged.
+type UpdateProfileRequest struct {
+	Email    string `json:"email" binding:"omitempty,email"`
+	Password string `json:"password" binding:"omitempty,min=6"`
+}
+
+// ProfilePatch is the shape of one's own profile that PATCH requests
+// apply to. The password is never filled in, so a patch only changes it
+// by setting it.
+type ProfilePatch struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"omitempty,min=6"`
+}
+
+// LoginResponse is the token of a user who logged in, along with the user
+type LoginResponse struct {
+	Token string       `json:"token"`
+	User  UserResponse `json:"user"`
+}
+
 type UserHandler struct {
 	userService *service.UserService
+	permissions middleware.PermissionResolver
 }
 
-func NewUserHandler(userService *service.UserService) *UserHandler {
+func NewUserHandler(userService *service.UserService, permissions middleware.PermissionResolver) *UserHandler {
 	return &UserHandler{
 		userService: userService,
+		permissions: permissions,
 	}
 }
 
@@ -24,13 +47,13 @@ func (h *UserHandler) Register(c *gin.Context) {
 	var req service.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Registration validation error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		failBinding(c, err)
 		return
 	}
 
 	if err := h.userService.Register(&req); err != nil {
 		logger.Error("Registration failed: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
@@ -42,19 +65,19 @@ func (h *UserHandler) Login(c *gin.Context) {
 	var req service.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Login validation error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		failBinding(c, err)
 		return
 	}
 
 	resp, err := h.userService.Login(&req)
 	if err != nil {
 		logger.Error("Login failed for user %s: %v", req.Username, err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
 	logger.Info("User logged in successfully: %s", req.Username)
-	c.JSON(http.StatusOK, resp)
+	c.JSON(http.StatusOK, LoginResponse{Token: resp.Token, User: newUserResponse(&resp.User)})
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
@@ -62,25 +85,26 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 	user, err := h.userService.GetUserByID(userID)
 	if err != nil {
 		logger.Error("Failed to get profile for user ID %d: %v", userID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, newUserResponse(user))
 }
 
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	userID := c.GetUint("userID")
-	var updates model.User
-	if err := c.ShouldBindJSON(&updates); err != nil {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.Error("Profile update validation error for user ID %d: %v", userID, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		failBinding(c, err)
 		return
 	}
 
-	if err := h.userService.UpdateUser(userID, &updates); err != nil {
+	updates := &model.User{Email: req.Email, Password: req.Password}
+	if err := h.userService.UpdateUser(userID, updates); err != nil {
 		logger.Error("Failed to update profile for user ID %d: %v", userID, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
@@ -88,29 +112,61 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
 }
 
+// PatchProfile handles a partial update of one's own profile, given as a
+// JSON Merge Patch or a JSON Patch of a ProfilePatch
+func (h *UserHandler) PatchProfile(c *gin.Context) {
+	userID := c.GetUint("userID")
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		logger.Error("Failed to get profile for user ID %d: %v", userID, err)
+		fail(c, err)
+		return
+	}
+
+	var req ProfilePatch
+	changed, ok := patchRequest(c, ProfilePatch{Email: user.Email}, &req, "email", "password")
+	if !ok {
+		return
+	}
+	if len(changed) > 0 {
+		updates := &model.User{Email: req.Email, Password: req.Password}
+		if err := h.userService.UpdateUser(userID, updates); err != nil {
+			logger.Error("Failed to patch profile for user ID %d: %v", userID, err)
+			fail(c, err)
+			return
+		}
+		if user, err = h.userService.GetUserByID(userID); err != nil {
+			logger.Error("Failed to get profile for user ID %d: %v", userID, err)
+			fail(c, err)
+			return
+		}
+		logger.Info("Profile patched successfully for user ID %d", userID)
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
 func (h *UserHandler) ListUsers(c *gin.Context) {
-	page := 1
-	limit := 10
-	// TODO: Get page and limit from query parameters
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
 
-	users, total, err := h.userService.ListUsers(page, limit)
+	page, err := h.userService.ListUsers(q)
 	if err != nil {
 		logger.Error("Failed to list users: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"users": users,
-		"total": total,
-	})
+	c.JSON(http.StatusOK, mapPage(page, newUserResponse))
 }
 
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	userID := c.GetUint("userID")
 	if err := h.userService.DeleteUser(userID); err != nil {
 		logger.Error("Failed to delete user ID %d: %v", userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
@@ -125,15 +181,16 @@ func (h *UserHandler) SetupRoutes(router *gin.Engine) {
 
 	// Protected routes
 	auth := router.Group("/api")
-	auth.Use(middleware.AuthMiddleware(h.userService.GetJWTSecret()))
+	auth.Use(middleware.AuthMiddleware(h.userService.GetJWTSecret()), middleware.LoadPermissions(h.permissions))
 	{
 		auth.GET("/profile", h.GetProfile)
 		auth.PUT("/profile", h.UpdateProfile)
+		auth.PATCH("/profile", h.PatchProfile)
 		auth.DELETE("/profile", h.DeleteUser)
 
 		// Admin routes
 		admin := auth.Group("/admin")
-		admin.Use(middleware.RequireRole("admin"))
+		admin.Use(middleware.RequirePermission(model.PermUsersAdmin))
 		{
 			admin.GET("/users", h.ListUsers)
 		}
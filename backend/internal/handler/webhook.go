@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"techdocs/internal/middleware"
+	"techdocs/internal/model"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookRequest is the body for creating or updating a webhook. Active
+// defaults to true; an empty secret is generated on creation and left
+// unchanged on update.
+type WebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookSecretResponse is a webhook along with its signing secret, which
+// is only shown when the webhook is created
+type WebhookSecretResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// RegisterRoutes registers the webhook administration routes
+func (h *WebhookHandler) RegisterRoutes(router *gin.RouterGroup) {
+	webhooks := router.Group("/admin/webhooks")
+	webhooks.Use(middleware.RequirePermission(model.PermWebhooksManage))
+	{
+		webhooks.GET("", h.GetWebhooks)
+		webhooks.POST("", h.CreateWebhook)
+		webhooks.GET("/events", h.GetEvents)
+		webhooks.GET("/:id", h.GetWebhook)
+		webhooks.PUT("/:id", h.UpdateWebhook)
+		webhooks.DELETE("/:id", h.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", h.GetDeliveries)
+		webhooks.POST("/:id/deliveries/:deliveryID/redeliver", h.Redeliver)
+	}
+}
+
+// GetWebhooks handles the retrieval of a page of webhooks
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.webhookService.GetWebhooks(q)
+	if err != nil {
+		logger.Error("Failed to get webhooks: %v", err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newWebhookResponse))
+}
+
+// GetEvents handles the retrieval of the events webhooks may subscribe to
+func (h *WebhookHandler) GetEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, service.WebhookEvents)
+}
+
+// CreateWebhook handles the registration of a new webhook. The response
+// carries the signing secret, which can't be retrieved afterwards.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Webhook creation validation error: %v", err)
+		failBinding(c, err)
+		return
+	}
+
+	hook := req.webhook()
+	secret, err := h.webhookService.CreateWebhook(hook, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to create webhook for %s: %v", req.URL, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Webhook created successfully: ID %d by user ID %d", hook.ID, c.GetUint("userID"))
+	c.JSON(http.StatusCreated, WebhookSecretResponse{WebhookResponse: newWebhookResponse(hook), Secret: secret})
+}
+
+// GetWebhook handles the retrieval of a webhook by its ID
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	hook, err := h.webhookService.GetWebhook(id)
+	if err != nil {
+		logger.Error("Failed to get webhook ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newWebhookResponse(hook))
+}
+
+// UpdateWebhook handles changing a webhook's URL, events, secret and
+// active flag
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Webhook update validation error for ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+
+	hook := req.webhook()
+	hook.ID = id
+	if err := h.webhookService.UpdateWebhook(hook); err != nil {
+		logger.Error("Failed to update webhook ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Webhook updated successfully: ID %d by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, newWebhookResponse(hook))
+}
+
+// DeleteWebhook handles the deletion of a webhook
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(id); err != nil {
+		logger.Error("Failed to delete webhook ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Webhook deleted successfully: ID %d by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetDeliveries handles the retrieval of a page of a webhook's delivery
+// log. Pass status=pending, succeeded or failed and type=<event> to
+// narrow it down.
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.webhookService.GetDeliveries(id, q)
+	if err != nil {
+		logger.Error("Failed to get deliveries of webhook ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newWebhookDeliveryResponse))
+}
+
+// Redeliver handles queueing a delivery to be sent again
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	deliveryIDStr := c.Param("deliveryID")
+	deliveryID, err := strconv.ParseUint(deliveryIDStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid delivery ID format: %s", deliveryIDStr)
+		badRequest(c, "invalid_id", "Invalid delivery ID format")
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(id, uint(deliveryID))
+	if err != nil {
+		logger.Error("Failed to redeliver delivery ID %d of webhook ID %d: %v", deliveryID, id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Delivery ID %d of webhook ID %d queued again by user ID %d", deliveryID, id, c.GetUint("userID"))
+	c.JSON(http.StatusAccepted, newWebhookDeliveryResponse(delivery))
+}
+
+func (r *WebhookRequest) webhook() *model.Webhook {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return &model.Webhook{
+		URL:    r.URL,
+		Events: r.Events,
+		Secret: r.Secret,
+		Active: active,
+	}
+}
+
+func parseWebhookID(c *gin.Context) (uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid webhook ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid webhook ID format")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"techdocs/internal/model"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReviewersRequest is the body for assigning the reviewers of a document.
+// An empty list removes all reviewers.
+type ReviewersRequest struct {
+	UserIDs []uint `json:"user_ids" binding:"required"`
+}
+
+// SubmitRequest is the optional body for submitting a document for review.
+// UserIDs, when given, replace the document's reviewers.
+type SubmitRequest struct {
+	UserIDs []uint `json:"user_ids"`
+}
+
+// ReviewRequest is the body of a reviewer's decision or remark. A comment
+// is optional when approving and required otherwise.
+type ReviewRequest struct {
+	Comment string `json:"comment"`
+}
+
+// GetReviewers handles the retrieval of a document's reviewers and their
+// decisions
+func (h *DocumentHandler) GetReviewers(c *gin.Context) {
+	id, ok := parseDocumentID(c)
+	if !ok {
+		return
+	}
+
+	reviewers, err := h.documentService.GetReviewers(id, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to get reviewers of document ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapSlice(reviewers, newReviewerResponse))
+}
+
+// SetReviewers handles replacing the reviewers of a document
+func (h *DocumentHandler) SetReviewers(c *gin.Context) {
+	id, ok := parseDocumentID(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Reviewers validation error for document ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+
+	reviewers, err := h.documentService.SetReviewers(id, req.UserIDs, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to set reviewers of document ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Reviewers of document ID %d set by user ID %d", id, c.GetUint("userID"))
+	c.JSON(http.StatusOK, mapSlice(reviewers, newReviewerResponse))
+}
+
+// SubmitDocument handles opening a review request for a draft, optionally
+// naming its reviewers
+func (h *DocumentHandler) SubmitDocument(c *gin.Context) {
+	var req SubmitRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("Submission validation error: %v", err)
+		failBinding(c, err)
+		return
+	}
+
+	h.transition(c, "submit", func(id uint, actor service.Actor) (*model.Document, error) {
+		return h.documentService.SubmitDocument(id, req.UserIDs, actor)
+	})
+}
+
+// WithdrawDocument handles taking a document back from review
+func (h *DocumentHandler) WithdrawDocument(c *gin.Context) {
+	h.transition(c, "withdraw", h.documentService.WithdrawDocument)
+}
+
+// PublishDocument handles publishing an approved document
+func (h *DocumentHandler) PublishDocument(c *gin.Context) {
+	h.transition(c, "publish", h.documentService.PublishDocument)
+}
+
+// ArchiveDocument handles archiving a published document
+func (h *DocumentHandler) ArchiveDocument(c *gin.Context) {
+	h.transition(c, "archive", h.documentService.ArchiveDocument)
+}
+
+// ReopenDocument handles turning an archived document back into a draft
+func (h *DocumentHandler) ReopenDocument(c *gin.Context) {
+	h.transition(c, "reopen", h.documentService.ReopenDocument)
+}
+
+// ApproveDocument handles a reviewer's sign-off on a document in review
+func (h *DocumentHandler) ApproveDocument(c *gin.Context) {
+	id, ok := parseDocumentID(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("Approval validation error for document ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+
+	doc, err := h.documentService.ApproveDocument(id, req.Comment, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to approve document ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Document ID %d approved by user ID %d", id, c.GetUint("userID"))
+	c.Header("ETag", etag(doc.Revision))
+	c.JSON(http.StatusOK, newDocumentResponse(doc))
+}
+
+// RequestChanges handles a reviewer sending a document back to its author
+func (h *DocumentHandler) RequestChanges(c *gin.Context) {
+	id, ok := parseDocumentID(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Change request validation error for document ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+	if req.Comment == "" {
+		badRequest(c, "invalid_request", "A comment describing the changes is required")
+		return
+	}
+
+	doc, err := h.documentService.RequestChanges(id, req.Comment, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to request changes to document ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Changes to document ID %d requested by user ID %d", id, c.GetUint("userID"))
+	c.Header("ETag", etag(doc.Revision))
+	c.JSON(http.StatusOK, newDocumentResponse(doc))
+}
+
+// CommentOnReview handles a reviewer's remark on a document in review
+// that neither approves nor requests changes
+func (h *DocumentHandler) CommentOnReview(c *gin.Context) {
+	id, ok := parseDocumentID(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Review comment validation error for document ID %d: %v", id, err)
+		failBinding(c, err)
+		return
+	}
+	if req.Comment == "" {
+		badRequest(c, "invalid_request", "Comment is required")
+		return
+	}
+
+	reviewer, err := h.documentService.CommentOnReview(id, req.Comment, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to comment on review of document ID %d: %v", id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("User ID %d commented on review of document ID %d", c.GetUint("userID"), id)
+	c.JSON(http.StatusOK, newReviewerResponse(reviewer))
+}
+
+// GetPendingReviews handles the retrieval of a page of the documents
+// waiting for the current user's review
+func (h *DocumentHandler) GetPendingReviews(c *gin.Context) {
+	q, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	actor := actorFrom(c)
+	page, err := h.documentService.GetPendingReviews(actor, q)
+	if err != nil {
+		logger.Error("Failed to get pending reviews of user ID %d: %v", actor.UserID, err)
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPage(page, newDocumentResponse))
+}
+
+// transition runs a status change of the document in the id parameter
+func (h *DocumentHandler) transition(c *gin.Context, action string, change func(uint, service.Actor) (*model.Document, error)) {
+	id, ok := parseDocumentID(c)
+	if !ok {
+		return
+	}
+
+	doc, err := change(id, actorFrom(c))
+	if err != nil {
+		logger.Error("Failed to %s document ID %d: %v", action, id, err)
+		fail(c, err)
+		return
+	}
+
+	logger.Info("Document ID %d is now %s after %s by user ID %d", id, doc.Status, action, c.GetUint("userID"))
+	c.Header("ETag", etag(doc.Revision))
+	c.JSON(http.StatusOK, newDocumentResponse(doc))
+}
+
+func parseDocumentID(c *gin.Context) (uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		logger.Error("Invalid document ID format: %s", idStr)
+		badRequest(c, "invalid_id", "Invalid document ID format")
+		return 0, false
+	}
+	return uint(id), true
+}
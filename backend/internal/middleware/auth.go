@@ -12,22 +12,19 @@ func AuthMiddleware(secret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
+			AbortWithProblem(c, http.StatusUnauthorized, "missing_token", "Authorization header is required")
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+			AbortWithProblem(c, http.StatusUnauthorized, "invalid_authorization_header", "Invalid authorization header format")
 			return
 		}
 
 		claims, err := auth.ValidateToken(parts[1], secret)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+			AbortWithProblem(c, http.StatusUnauthorized, "invalid_token", "Invalid token")
 			return
 		}
 
@@ -39,25 +36,3 @@ func AuthMiddleware(secret string) gin.HandlerFunc {
 		c.Next()
 	}
 }
-
-func RequireRole(roles ...string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userRole, exists := c.Get("role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
-			c.Abort()
-			return
-		}
-
-		role := userRole.(string)
-		for _, r := range roles {
-			if r == role {
-				c.Next()
-				return
-			}
-		}
-
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
-		c.Abort()
-	}
-} 
\ No newline at end of file
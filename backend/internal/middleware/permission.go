@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"techdocs/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionResolver looks up the permissions granted to a user
+type PermissionResolver interface {
+	PermissionsForUser(userID uint) ([]string, error)
+}
+
+// LoadPermissions stores the authenticated user's permissions in the
+// context. It must run after AuthMiddleware.
+func LoadPermissions(resolver PermissionResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("userID")
+		permissions, err := resolver.PermissionsForUser(userID)
+		if err != nil {
+			logger.Error("Failed to load permissions for user ID %d: %v", userID, err)
+			AbortWithProblem(c, http.StatusInternalServerError, "internal_error", "Failed to load permissions")
+			return
+		}
+
+		c.Set("permissions", permissions)
+		c.Next()
+	}
+}
+
+// RequirePermission only lets requests through when the user holds the
+// permission. It must run after LoadPermissions.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, p := range c.GetStringSlice("permissions") {
+			if p == permission {
+				c.Next()
+				return
+			}
+		}
+
+		logger.Error("Authorization denied: user ID %d lacks permission %s for %s %s",
+			c.GetUint("userID"), permission, c.Request.Method, c.Request.URL.Path)
+		AbortWithProblem(c, http.StatusForbidden, "insufficient_permissions", "Insufficient permissions")
+	}
+}
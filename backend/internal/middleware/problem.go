@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"techdocs/internal/repository"
+	"techdocs/internal/service"
+	"techdocs/pkg/logger"
+	"techdocs/pkg/patch"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// ProblemContentType is the media type of problem responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Code is a stable, machine
+// readable identifier of the error and Errors lists the request fields
+// that failed validation. Extensions are added as further members.
+type Problem struct {
+	Type       string         `json:"type"`
+	Title      string         `json:"title"`
+	Status     int            `json:"status"`
+	Detail     string         `json:"detail,omitempty"`
+	Instance   string         `json:"instance,omitempty"`
+	Code       string         `json:"code"`
+	RequestID  string         `json:"request_id,omitempty"`
+	Errors     []FieldError   `json:"errors,omitempty"`
+	Extensions map[string]any `json:"-"`
+}
+
+// FieldError is a request field that failed validation, along with the
+// rule it broke and the rule's parameter, if any
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+}
+
+// MarshalJSON adds the extensions to the standard members
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	data, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	var members map[string]any
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	for name, value := range p.Extensions {
+		if _, ok := members[name]; !ok {
+			members[name] = value
+		}
+	}
+	return json.Marshal(members)
+}
+
+// kindStatus maps the kinds of domain errors to response statuses
+var kindStatus = map[service.Kind]int{
+	service.KindNotFound:     http.StatusNotFound,
+	service.KindConflict:     http.StatusConflict,
+	service.KindValidation:   http.StatusBadRequest,
+	service.KindForbidden:    http.StatusForbidden,
+	service.KindDuplicate:    http.StatusConflict,
+	service.KindUnauthorized: http.StatusUnauthorized,
+}
+
+// registerFieldNames makes validation errors name fields as they appear
+// in JSON
+var registerFieldNames sync.Once
+
+// Errors writes the last error a handler added with c.Error as a problem
+// response. Domain errors keep their code; errors of the kind
+// gin.ErrorTypeBind are reported as malformed requests. Anything else is
+// logged and answered with a 500 that doesn't reveal its cause. Meta of
+// the error, when a gin.H, is added to the problem as extensions.
+func Errors() gin.HandlerFunc {
+	registerFieldNames.Do(func() {
+		if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+			v.RegisterTagNameFunc(jsonFieldName)
+		}
+	})
+
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil || c.Writer.Written() {
+			return
+		}
+
+		p := problemFor(last)
+		if p.Status == http.StatusInternalServerError {
+			logger.Error("Request %s %s failed (request ID %s): %v",
+				c.Request.Method, c.Request.URL.Path, c.GetString("requestID"), last.Err)
+		}
+		if meta, ok := last.Meta.(gin.H); ok {
+			p.Extensions = meta
+		}
+		writeProblem(c, p)
+	}
+}
+
+// AbortWithProblem writes a problem response for an error that only
+// exists at the HTTP level, such as a malformed path parameter or a
+// missing token, and stops the chain
+func AbortWithProblem(c *gin.Context, status int, code, detail string) {
+	writeProblem(c, Problem{Status: status, Code: code, Detail: detail})
+}
+
+// problemFor maps an error to the problem describing it
+func problemFor(e *gin.Error) Problem {
+	err := e.Err
+	if e.IsType(gin.ErrorTypeBind) {
+		return bindingProblem(err)
+	}
+
+	var domain *service.Error
+	switch {
+	case errors.As(err, &domain):
+		status, ok := kindStatus[domain.Kind]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		return Problem{Status: status, Code: domain.Code, Detail: err.Error()}
+	case errors.Is(err, repository.ErrRevisionConflict):
+		return Problem{Status: http.StatusConflict, Code: "revision_conflict", Detail: err.Error()}
+	case errors.Is(err, repository.ErrPageCycle):
+		return Problem{Status: http.StatusBadRequest, Code: "page_cycle", Detail: err.Error()}
+	case errors.Is(err, repository.ErrInvalidSort):
+		return Problem{Status: http.StatusBadRequest, Code: "invalid_sort", Detail: err.Error()}
+	case errors.Is(err, repository.ErrInvalidCursor):
+		return Problem{Status: http.StatusBadRequest, Code: "invalid_cursor", Detail: err.Error()}
+	case errors.Is(err, patch.ErrInvalidPatch):
+		return Problem{Status: http.StatusBadRequest, Code: "invalid_patch", Detail: err.Error()}
+	case errors.Is(err, patch.ErrTestFailed):
+		return Problem{Status: http.StatusConflict, Code: "patch_test_failed", Detail: err.Error()}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return Problem{Status: http.StatusNotFound, Code: "not_found", Detail: "resource not found"}
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return Problem{Status: kindStatus[service.KindDuplicate], Code: "duplicate", Detail: "a resource with the same unique values already exists"}
+	default:
+		return Problem{
+			Status: http.StatusInternalServerError,
+			Code:   "internal_error",
+			Detail: "an unexpected error occurred, please report it along with the request ID",
+		}
+	}
+}
+
+// bindingProblem describes a request body or query that couldn't be bound
+func bindingProblem(err error) Problem {
+	p := Problem{Status: http.StatusBadRequest, Code: "invalid_request", Detail: "request failed validation"}
+
+	var fields validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &fields):
+		for _, f := range fields {
+			p.Errors = append(p.Errors, FieldError{Field: fieldPath(f), Rule: f.Tag(), Param: f.Param()})
+		}
+	case errors.As(err, &typeErr):
+		p.Errors = []FieldError{{Field: typeErr.Field, Rule: "type", Param: typeErr.Type.String()}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		p.Detail = "request body is not valid JSON"
+	case errors.Is(err, io.EOF):
+		p.Detail = "request body is required"
+	default:
+		p.Detail = err.Error()
+	}
+	return p
+}
+
+// fieldPath returns the JSON path of a field that failed validation,
+// without the name of the request type
+func fieldPath(f validator.FieldError) string {
+	path := f.Namespace()
+	if i := strings.Index(path, "."); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// jsonFieldName names a struct field by its JSON name
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// writeProblem completes a problem with what is known about the request
+// and writes it
+func writeProblem(c *gin.Context, p Problem) {
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+	p.Instance = c.Request.URL.Path
+	p.RequestID = c.GetString("requestID")
+
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
@@ -1,6 +1,9 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -12,21 +15,152 @@ type User struct {
 	Email     string     `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
 	Password  string     `gorm:"type:varchar(255);not null" json:"-"`
 	Role      string     `gorm:"type:varchar(50);not null;default:'user'" json:"role"`
+	Digest    string     `gorm:"type:varchar(20);not null;default:'immediate'" json:"digest"`
 	Documents []Document `gorm:"foreignKey:AuthorID" json:"documents,omitempty"`
 }
 
+// Permission names checked by RequirePermission and the service layer
+const (
+	PermDocumentsWrite   = "documents:write"
+	PermDocumentsAdmin   = "documents:admin"
+	PermServicesManage   = "services:manage"
+	PermTeamsCreate      = "teams:create"
+	PermTeamsAdmin       = "teams:admin"
+	PermUsersAdmin       = "users:admin"
+	PermWebhooksManage   = "webhooks:manage"
+	PermTrashPurge       = "trash:purge"
+	PermSpacesManage     = "spaces:manage"
+	PermCategoriesManage = "categories:manage"
+	PermTagsManage       = "tags:manage"
+)
+
+// Permission is a single capability that can be bundled into roles
+type Permission struct {
+	gorm.Model
+	Name        string `gorm:"type:varchar(100);uniqueIndex;not null" json:"name"`
+	Description string `json:"description"`
+}
+
+// Role is a named bundle of permissions. Users reference their role by name
+// through User.Role.
+type Role struct {
+	gorm.Model
+	Name        string       `gorm:"type:varchar(50);uniqueIndex;not null" json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions"`
+}
+
+// Document lifecycle states. A document starts as a draft, goes through
+// review and is published once approved. Editing a published document
+// starts a new draft, while PublishedVersion keeps the published state
+// visible to readers until the draft is published in turn. Documents
+// created before the workflow existed count as published.
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusApproved  = "approved"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
 type Document struct {
 	gorm.Model
-	Title       string   `json:"title" gorm:"not null"`
-	Description string   `json:"description"`
-	Content     string   `json:"content" gorm:"type:text"`
-	Type        string   `json:"type" gorm:"not null"`
-	Category    string   `json:"category"`
-	AuthorID    uint     `json:"author_id" gorm:"not null"`
-	Author      User     `json:"author" gorm:"foreignKey:AuthorID"`
-	Tags        []Tag    `json:"tags" gorm:"many2many:document_tags;"`
-	ServiceID   *uint    `json:"service_id"`
-	Service     *Service `json:"service"`
+	Title            string    `json:"title" gorm:"not null;index:idx_documents_fulltext,class:FULLTEXT"`
+	Description      string    `json:"description" gorm:"index:idx_documents_fulltext,class:FULLTEXT"`
+	Content          string    `json:"content" gorm:"type:text;index:idx_documents_fulltext,class:FULLTEXT"`
+	Type             string    `json:"type" gorm:"not null"`
+	CategoryID       *uint     `json:"category_id" gorm:"index"`
+	Category         *Category `json:"category,omitempty"`
+	AuthorID         uint      `json:"author_id" gorm:"not null"`
+	Author           User      `json:"author" gorm:"foreignKey:AuthorID"`
+	Tags             []Tag     `json:"tags" gorm:"many2many:document_tags;"`
+	ServiceID        *uint     `json:"service_id"`
+	Service          *Service  `json:"service"`
+	Revision         uint      `json:"revision" gorm:"not null;default:1"`
+	Restricted       bool      `json:"restricted" gorm:"not null;default:false"`
+	TeamID           *uint     `json:"team_id"`
+	Team             *Team     `json:"team,omitempty"`
+	Status           string    `json:"status" gorm:"type:varchar(20);not null;default:'published';index"`
+	PublishedVersion *int      `json:"published_version,omitempty"`
+	SpaceID          *uint     `json:"space_id" gorm:"index"`
+	Space            *Space    `json:"space,omitempty"`
+	ParentID         *uint     `json:"parent_id" gorm:"index"`
+	Position         int       `json:"position" gorm:"not null;default:0"`
+}
+
+// Space groups documents, for instance per product or team, into trees of
+// pages. A document's ParentID points at the page it sits below, and
+// Position orders it among its siblings.
+type Space struct {
+	gorm.Model
+	Name        string `gorm:"type:varchar(255);uniqueIndex;not null" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+	TeamID      *uint  `json:"team_id"`
+	Team        *Team  `json:"team,omitempty"`
+}
+
+// Review decisions
+const (
+	ReviewPending          = "pending"
+	ReviewApproved         = "approved"
+	ReviewChangesRequested = "changes_requested"
+	ReviewCommented        = "commented"
+)
+
+// DocumentReviewer is a user asked to sign off on a document before it is
+// published, along with their decision on the current submission.
+// Reviewers who only commented count as undecided.
+type DocumentReviewer struct {
+	gorm.Model
+	DocumentID uint       `gorm:"not null;uniqueIndex:idx_document_reviewer" json:"document_id"`
+	UserID     uint       `gorm:"not null;uniqueIndex:idx_document_reviewer;index" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID" json:"user"`
+	Decision   string     `gorm:"type:varchar(20);not null;default:'pending'" json:"decision"`
+	Comment    string     `gorm:"type:text" json:"comment,omitempty"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty"`
+}
+
+// Permission levels on a document, from weakest to strongest
+const (
+	PermissionViewer = "viewer"
+	PermissionEditor = "editor"
+	PermissionOwner  = "owner"
+)
+
+// DocumentPermission grants a user or a team access to a document; exactly
+// one of UserID and TeamID is set. Restricted documents are only visible to
+// their author, admins, members of the owning team and grantees.
+type DocumentPermission struct {
+	gorm.Model
+	DocumentID uint   `gorm:"not null;uniqueIndex:idx_document_permission_user;uniqueIndex:idx_document_permission_team" json:"document_id"`
+	UserID     *uint  `gorm:"uniqueIndex:idx_document_permission_user" json:"user_id,omitempty"`
+	User       *User  `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	TeamID     *uint  `gorm:"uniqueIndex:idx_document_permission_team" json:"team_id,omitempty"`
+	Team       *Team  `gorm:"foreignKey:TeamID" json:"team,omitempty"`
+	Level      string `gorm:"type:varchar(20);not null" json:"level"`
+	GrantedBy  uint   `gorm:"not null" json:"granted_by"`
+}
+
+// Roles within a team. Team admins manage membership and act as owners of
+// the team's documents, members act as editors.
+const (
+	TeamRoleMember = "member"
+	TeamRoleAdmin  = "admin"
+)
+
+type Team struct {
+	gorm.Model
+	Name        string       `gorm:"type:varchar(255);uniqueIndex;not null" json:"name"`
+	Description string       `json:"description"`
+	Members     []TeamMember `gorm:"foreignKey:TeamID" json:"members,omitempty"`
+}
+
+type TeamMember struct {
+	gorm.Model
+	TeamID uint   `gorm:"not null;uniqueIndex:idx_team_member" json:"team_id"`
+	UserID uint   `gorm:"not null;uniqueIndex:idx_team_member" json:"user_id"`
+	User   User   `gorm:"foreignKey:UserID" json:"user"`
+	Role   string `gorm:"type:varchar(20);not null;default:'member'" json:"role"`
 }
 
 type Tag struct {
@@ -35,26 +169,206 @@ type Tag struct {
 	Documents []Document `gorm:"many2many:document_tags;" json:"documents,omitempty"`
 }
 
+// Comment is a remark on a document. A comment without a parent starts a
+// thread and replies point at it through ParentID. Only the first comment
+// of a thread carries the optional anchor, either a heading or a line range
+// in the document's content, and the resolved state of the whole thread.
 type Comment struct {
 	gorm.Model
-	Content    string   `gorm:"type:text;not null" json:"content"`
-	DocumentID uint     `gorm:"not null" json:"document_id"`
-	Document   Document `gorm:"foreignKey:DocumentID" json:"document,omitempty"`
-	UserID     uint     `gorm:"not null" json:"user_id"`
-	User       User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Content         string     `gorm:"type:text;not null;index:idx_comments_fulltext,class:FULLTEXT" json:"content"`
+	DocumentID      uint       `gorm:"not null;index" json:"document_id"`
+	Document        Document   `gorm:"foreignKey:DocumentID" json:"-"`
+	UserID          uint       `gorm:"not null" json:"user_id"`
+	User            User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	ParentID        *uint      `gorm:"index" json:"parent_id"`
+	Replies         []Comment  `gorm:"foreignKey:ParentID" json:"replies,omitempty"`
+	AnchorHeading   string     `gorm:"type:varchar(255)" json:"anchor_heading,omitempty"`
+	AnchorStartLine *int       `json:"anchor_start_line,omitempty"`
+	AnchorEndLine   *int       `json:"anchor_end_line,omitempty"`
+	Resolved        bool       `gorm:"not null;default:false" json:"resolved"`
+	ResolvedBy      *uint      `json:"resolved_by,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
 }
 
+// Notification types
+const (
+	NotificationMention           = "mention"
+	NotificationDocumentCreated   = "document_created"
+	NotificationDocumentUpdated   = "document_updated"
+	NotificationDocumentDeleted   = "document_deleted"
+	NotificationComment           = "comment"
+	NotificationDocumentPublished = "document_published"
+	NotificationReviewRequested   = "review_requested"
+	NotificationReviewApproved    = "review_approved"
+	NotificationChangesRequested  = "changes_requested"
+)
+
+// Digest preferences decide whether a user hears about notifications
+// outside the app as they happen or once a day
+const (
+	DigestImmediate = "immediate"
+	DigestDaily     = "daily"
+)
+
+// Subscription targets
+const (
+	SubscriptionDocument = "document"
+	SubscriptionService  = "service"
+	SubscriptionTag      = "tag"
+)
+
+// Subscription makes a user hear about changes to a document, to every
+// document linked to a service or to every document with a tag
+type Subscription struct {
+	gorm.Model
+	UserID     uint   `gorm:"not null;uniqueIndex:idx_subscription" json:"user_id"`
+	TargetType string `gorm:"type:varchar(20);not null;uniqueIndex:idx_subscription" json:"target_type"`
+	TargetID   uint   `gorm:"not null;uniqueIndex:idx_subscription" json:"target_id"`
+}
+
+// Notification tells a user about something another user did. ReadAt is
+// nil until the user marks it as read, EmailedAt until it was delivered by
+// e-mail, either on its own or in a digest.
+type Notification struct {
+	gorm.Model
+	UserID        uint       `gorm:"not null;index:idx_notification_user" json:"user_id"`
+	User          User       `gorm:"foreignKey:UserID" json:"-"`
+	ActorID       uint       `gorm:"not null" json:"actor_id"`
+	Actor         User       `gorm:"foreignKey:ActorID" json:"actor"`
+	Type          string     `gorm:"type:varchar(50);not null" json:"type"`
+	Message       string     `gorm:"type:varchar(500);not null" json:"message"`
+	DocumentID    *uint      `json:"document_id,omitempty"`
+	CommentID     *uint      `json:"comment_id,omitempty"`
+	ReadAt        *time.Time `gorm:"index:idx_notification_user" json:"read_at"`
+	EmailedAt     *time.Time `gorm:"index" json:"-"`
+	EmailAttempts int        `gorm:"not null;default:0" json:"-"`
+}
+
+// DocumentVersion is a snapshot of a document as it was before an update
+// replaced it. Version numbers are sequential per document, starting at 1.
+// CreatedBy is the user whose change replaced the snapshot, and RestoredFrom
+// is set when that change was a restore of an older version.
 type DocumentVersion struct {
 	gorm.Model
-	DocumentID uint      `gorm:"not null" json:"document_id"`
-	Document   Document  `gorm:"foreignKey:DocumentID" json:"document,omitempty"`
-	Content    string    `gorm:"type:text" json:"content"`
-	Version    int       `gorm:"not null" json:"version"`
-	CreatedBy  uint      `gorm:"not null" json:"created_by"`
-	CreatedAt  time.Time `json:"created_at"`
+	DocumentID   uint       `gorm:"not null;uniqueIndex:idx_document_version" json:"document_id"`
+	Document     Document   `gorm:"foreignKey:DocumentID" json:"-"`
+	Version      int        `gorm:"not null;uniqueIndex:idx_document_version" json:"version"`
+	Title        string     `gorm:"index:idx_document_versions_fulltext,class:FULLTEXT" json:"title"`
+	Description  string     `gorm:"index:idx_document_versions_fulltext,class:FULLTEXT" json:"description"`
+	Content      string     `gorm:"type:text;index:idx_document_versions_fulltext,class:FULLTEXT" json:"content"`
+	Type         string     `json:"type"`
+	CategoryID   *uint      `json:"category_id"`
+	Tags         StringList `gorm:"type:text" json:"tags"`
+	ServiceID    *uint      `json:"service_id"`
+	CreatedBy    uint       `gorm:"not null" json:"created_by"`
+	RestoredFrom *int       `json:"restored_from,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
 }
 
 type Service struct {
 	gorm.Model
-	Name string `gorm:"not null" json:"name"`
+	Name       string    `gorm:"not null;index:idx_services_fulltext,class:FULLTEXT" json:"name"`
+	Revision   uint      `gorm:"not null;default:1" json:"revision"`
+	TeamID     *uint     `json:"team_id"`
+	Team       *Team     `json:"team,omitempty"`
+	CategoryID *uint     `gorm:"index" json:"category_id"`
+	Category   *Category `json:"category,omitempty"`
+}
+
+// Category classifies documents and services. Slug is the stable name
+// used in URLs and filters, Position orders categories for display.
+type Category struct {
+	gorm.Model
+	Name        string `gorm:"type:varchar(100);uniqueIndex;not null" json:"name"`
+	Slug        string `gorm:"type:varchar(100);uniqueIndex;not null" json:"slug"`
+	Description string `gorm:"type:text" json:"description"`
+	Position    int    `gorm:"not null;default:0" json:"position"`
+	// RequiredApprovals is how many approvals documents in the category
+	// need before they are published, 0 for the configured default
+	RequiredApprovals int `gorm:"not null;default:0" json:"required_approvals"`
+}
+
+// StringList is a list of strings stored as a JSON array in a text column
+type StringList []string
+
+// Value implements driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (l *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = StringList{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for StringList")
+	}
+	if len(data) == 0 {
+		*l = StringList{}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// Webhook event types. A webhook subscribes to them by name or with a
+// wildcard such as "service.*".
+const (
+	EventDocumentCreated   = "document.created"
+	EventDocumentUpdated   = "document.updated"
+	EventDocumentDeleted   = "document.deleted"
+	EventDocumentPublished = "document.published"
+	EventDocumentRestored  = "document.restored"
+	EventServiceCreated    = "service.created"
+	EventServiceUpdated    = "service.updated"
+	EventServiceDeleted    = "service.deleted"
+	EventServiceRestored   = "service.restored"
+	EventCommentCreated    = "comment.created"
+)
+
+// Webhook posts signed event payloads to a URL
+type Webhook struct {
+	gorm.Model
+	URL       string     `gorm:"type:varchar(2048);not null" json:"url"`
+	Events    StringList `gorm:"type:text" json:"events"`
+	Secret    string     `gorm:"type:varchar(255);not null" json:"-"`
+	Active    bool       `gorm:"not null" json:"active"`
+	CreatedBy uint       `gorm:"not null" json:"created_by"`
+}
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event queued for a webhook, along with the
+// outcome of the latest attempt. Pending deliveries are retried at
+// NextAttemptAt until they succeed or run out of attempts.
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID      uint       `gorm:"not null;index" json:"webhook_id"`
+	Webhook        Webhook    `gorm:"foreignKey:WebhookID" json:"-"`
+	Event          string     `gorm:"type:varchar(50);not null" json:"event"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	Status         string     `gorm:"type:varchar(20);not null;index:idx_webhook_delivery_due" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"not null;index:idx_webhook_delivery_due" json:"next_attempt_at"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	Error          string     `gorm:"type:text" json:"error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
 }
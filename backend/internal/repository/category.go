@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"techdocs/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type CategoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// Create creates a new category
+func (r *CategoryRepository) Create(category *model.Category) error {
+	return r.db.Create(category).Error
+}
+
+// Update updates a category's name, slug, description and position
+func (r *CategoryRepository) Update(category *model.Category) error {
+	return r.db.Model(category).Select("Name", "Slug", "Description", "Position", "RequiredApprovals").Updates(category).Error
+}
+
+// Delete deletes a category for good. Documents, their versions and
+// services in it, deleted ones included, move to another category or to
+// none when moveTo is nil.
+func (r *CategoryRepository) Delete(id uint, moveTo *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped().Session(&gorm.Session{})
+		for _, classified := range []interface{}{&model.Document{}, &model.DocumentVersion{}, &model.Service{}} {
+			err := tx.Model(classified).Where("category_id = ?", id).UpdateColumn("category_id", moveTo).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&model.Category{}, id).Error
+	})
+}
+
+// GetByID retrieves a category by its ID
+func (r *CategoryRepository) GetByID(id uint) (*model.Category, error) {
+	var category model.Category
+	if err := r.db.First(&category, id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// Taken reports whether a category other than exceptID uses a name or a
+// slug
+func (r *CategoryRepository) Taken(name, slug string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Category{}).
+		Where("(name = ? OR slug = ?) AND id <> ?", name, slug, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// NextPosition returns the position after the last category
+func (r *CategoryRepository) NextPosition() (int, error) {
+	var count int64
+	err := r.db.Model(&model.Category{}).Count(&count).Error
+	return int(count), err
+}
+
+// categorySorts are the fields a category list may be sorted by
+var categorySorts = []string{"id", "name", "slug", "position", "created_at"}
+
+// List retrieves one page of categories, in display order by default
+func (r *CategoryRepository) List(q ListQuery) (*Page[model.Category], error) {
+	return paginate[model.Category](r.db, "categories", q, categorySorts, "position")
+}
+
+// CountDocuments returns how many documents the viewer may see in each of
+// the given categories
+func (r *CategoryRepository) CountDocuments(ids []uint, viewer Viewer) (map[uint]int64, error) {
+	return countBy(r.db.Model(&model.Document{}).Scopes(visibleTo(viewer)), "documents.category_id", ids)
+}
+
+// CountServices returns how many services are in each of the given
+// categories
+func (r *CategoryRepository) CountServices(ids []uint) (map[uint]int64, error) {
+	return countBy(r.db.Model(&model.Service{}), "services.category_id", ids)
+}
+
+// InUse reports whether documents or services are in a category
+func (r *CategoryRepository) InUse(id uint) (bool, error) {
+	for _, classified := range []interface{}{&model.Document{}, &model.Service{}} {
+		var count int64
+		if err := r.db.Model(classified).Where("category_id = ?", id).Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// countBy counts the rows of a query for each of the given values of a
+// column
+func countBy(db *gorm.DB, column string, ids []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ID    uint
+		Count int64
+	}
+	err := db.Select(column+" AS id, COUNT(*) AS count").
+		Where(column+" IN ?", ids).Group(column).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ID] = row.Count
+	}
+	return counts, nil
+}
+
+// categoryIDs is a subquery for the IDs of the categories with a slug or
+// name, so filters accept either
+func categoryIDs(db *gorm.DB, value string) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(&model.Category{}).
+		Select("id").Where("slug = ? OR name = ?", value, value)
+}
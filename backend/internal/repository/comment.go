@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"techdocs/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CommentRepository struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// Create creates a new comment
+func (r *CommentRepository) Create(comment *model.Comment) error {
+	return r.db.Omit(clause.Associations).Create(comment).Error
+}
+
+// Update saves a comment's content and anchor
+func (r *CommentRepository) Update(comment *model.Comment) error {
+	return r.db.Model(comment).
+		Select("Content", "AnchorHeading", "AnchorStartLine", "AnchorEndLine").
+		Updates(comment).Error
+}
+
+// SetResolved marks a thread as resolved by a user, or reopens it
+func (r *CommentRepository) SetResolved(id uint, resolved bool, userID uint) error {
+	updates := map[string]interface{}{
+		"resolved":    resolved,
+		"resolved_by": nil,
+		"resolved_at": nil,
+	}
+	if resolved {
+		updates["resolved_by"] = userID
+		updates["resolved_at"] = time.Now()
+	}
+	return r.db.Model(&model.Comment{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Delete deletes a comment along with its replies
+func (r *CommentRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("parent_id = ?", id).Delete(&model.Comment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Comment{}, id).Error
+	})
+}
+
+// GetByID retrieves a comment with its author and replies
+func (r *CommentRepository) GetByID(id uint) (*model.Comment, error) {
+	var comment model.Comment
+	err := r.db.Preload("User").Preload("Replies", inWrittenOrder).Preload("Replies.User").First(&comment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// commentSorts are the fields a thread list may be sorted by
+var commentSorts = []string{"id", "created_at", "updated_at"}
+
+// ListThreads retrieves one page of a document's threads, each with its
+// replies in the order they were written
+func (r *CommentRepository) ListThreads(documentID uint, q ListQuery) (*Page[model.Comment], error) {
+	f := q.Filter
+	db := r.db.Scopes(createdBetween("comments", f), updatedBetween("comments", f)).
+		Where("comments.document_id = ? AND comments.parent_id IS NULL", documentID)
+	if f.Resolved != nil {
+		db = db.Where("comments.resolved = ?", *f.Resolved)
+	}
+	if f.AuthorID != nil {
+		db = db.Where("comments.user_id = ?", *f.AuthorID)
+	}
+	page, err := paginate[model.Comment](db, "comments", q, commentSorts, "created_at", "User")
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadReplies(page.Items); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// inWrittenOrder orders comments by when they were written
+func inWrittenOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("comments.created_at, comments.id")
+}
+
+// loadReplies fills in the replies of threads, with their authors, in the
+// order they were written
+func (r *CommentRepository) loadReplies(threads []model.Comment) error {
+	if len(threads) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(threads))
+	for i, thread := range threads {
+		ids[i] = thread.ID
+	}
+
+	var replies []model.Comment
+	err := r.db.Preload("User").Scopes(inWrittenOrder).Where("parent_id IN ?", ids).Find(&replies).Error
+	if err != nil {
+		return err
+	}
+
+	byThread := make(map[uint][]model.Comment, len(threads))
+	for _, reply := range replies {
+		byThread[*reply.ParentID] = append(byThread[*reply.ParentID], reply)
+	}
+	for i := range threads {
+		threads[i].Replies = byThread[threads[i].ID]
+	}
+	return nil
+}
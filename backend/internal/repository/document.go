@@ -1,12 +1,20 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
+	"strings"
 	"techdocs/internal/model"
 	"techdocs/pkg/logger"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrRevisionConflict is returned when an update is based on a revision
+// that is no longer the stored one
+var ErrRevisionConflict = errors.New("resource was modified by someone else")
+
 type DocumentRepository struct {
 	db *gorm.DB
 }
@@ -15,9 +23,138 @@ func NewDocumentRepository(db *gorm.DB) *DocumentRepository {
 	return &DocumentRepository{db: db}
 }
 
-// Create creates a new document in the database
+// Viewer identifies the user a query runs for, so restricted documents can
+// be filtered out
+type Viewer struct {
+	UserID uint
+	Admin  bool
+}
+
+// visibleTo limits a document query to what the viewer may see: the
+// documents open to them (see openTo) that are or were published, plus
+// drafts the viewer may see (see draftAccess)
+func visibleTo(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer.Admin {
+			return db
+		}
+		return db.Scopes(openTo(viewer)).Where(
+			db.Session(&gorm.Session{NewDB: true}).
+				Where("documents.status IN ? OR documents.published_version IS NOT NULL", liveStatuses).
+				Or(draftAccessCondition(viewer)),
+		)
+	}
+}
+
+// openTo limits a document query to every unrestricted document, plus
+// restricted ones the viewer authored, owns through a team or holds a
+// permission on
+func openTo(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer.Admin {
+			return db
+		}
+		return db.Where(
+			"documents.restricted = @restricted OR documents.author_id = @user "+
+				"OR documents.team_id IN (SELECT tm.team_id FROM team_members tm WHERE tm.user_id = @user AND tm.deleted_at IS NULL) "+
+				"OR EXISTS (SELECT 1 FROM document_permissions dp "+
+				"WHERE dp.document_id = documents.id AND dp.deleted_at IS NULL AND (dp.user_id = @user "+
+				"OR dp.team_id IN (SELECT tm.team_id FROM team_members tm WHERE tm.user_id = @user AND tm.deleted_at IS NULL)))",
+			sql.Named("restricted", false),
+			sql.Named("user", viewer.UserID),
+		)
+	}
+}
+
+// liveStatuses are the statuses in which a document's stored state is the
+// one readers see
+var liveStatuses = []string{model.StatusPublished, model.StatusArchived}
+
+// draftAccessCondition matches the documents whose drafts the viewer may
+// see: those they author, review, own through a team or may edit through
+// a grant
+func draftAccessCondition(viewer Viewer) clause.NamedExpr {
+	return clause.NamedExpr{
+		SQL: "documents.author_id = @user " +
+			"OR documents.team_id IN (SELECT tm.team_id FROM team_members tm WHERE tm.user_id = @user AND tm.deleted_at IS NULL) " +
+			"OR EXISTS (SELECT 1 FROM document_reviewers dr " +
+			"WHERE dr.document_id = documents.id AND dr.user_id = @user AND dr.deleted_at IS NULL) " +
+			"OR EXISTS (SELECT 1 FROM document_permissions dp " +
+			"WHERE dp.document_id = documents.id AND dp.deleted_at IS NULL AND dp.level IN @levels AND (dp.user_id = @user " +
+			"OR dp.team_id IN (SELECT tm.team_id FROM team_members tm WHERE tm.user_id = @user AND tm.deleted_at IS NULL)))",
+		Vars: []interface{}{
+			sql.Named("user", viewer.UserID),
+			sql.Named("levels", []string{model.PermissionEditor, model.PermissionOwner}),
+		},
+	}
+}
+
+// draftAccess limits a document query to the documents whose drafts the
+// viewer may see
+func draftAccess(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer.Admin {
+			return db
+		}
+		return db.Where(draftAccessCondition(viewer))
+	}
+}
+
+// currentVisibleTo limits a visible document query further to documents
+// whose stored state the viewer may see, leaving out drafts of published
+// documents that are shown to the viewer as published instead. It is for
+// queries that match against the stored content, such as search.
+func currentVisibleTo(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer.Admin {
+			return db
+		}
+		return db.Where(
+			db.Session(&gorm.Session{NewDB: true}).
+				Where("documents.status IN ?", liveStatuses).
+				Or(draftAccessCondition(viewer)),
+		)
+	}
+}
+
+// publishedOnlyTo limits a visible document query to the documents being
+// redrafted that the viewer sees as their published version, the ones
+// currentVisibleTo leaves out
+func publishedOnlyTo(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer.Admin {
+			return db.Where("FALSE")
+		}
+		access := draftAccessCondition(viewer)
+		return db.Where("documents.published_version IS NOT NULL AND documents.status NOT IN ?", liveStatuses).
+			Where(clause.NamedExpr{SQL: "NOT (" + access.SQL + ")", Vars: access.Vars})
+	}
+}
+
+// ownedByTeam limits a query to rows owned by a team, when one is given
+func ownedByTeam(teamID *uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if teamID == nil {
+			return db
+		}
+		return db.Where("team_id = ?", *teamID)
+	}
+}
+
+// Create creates a new document in the database. Its tags are looked up
+// by name and created when missing.
 func (r *DocumentRepository) Create(document *model.Document) error {
-	return r.db.Create(document).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		tags, err := findOrCreateTags(tx, tagNames(document.Tags))
+		if err != nil {
+			return err
+		}
+		if err := tx.Omit("Tags", "Team", "Space", "Category").Create(document).Error; err != nil {
+			return err
+		}
+		document.Tags = tags
+		return tx.Model(document).Association("Tags").Replace(tags)
+	})
 }
 
 // Update updates an existing document in the database
@@ -25,59 +162,588 @@ func (r *DocumentRepository) Update(document *model.Document) error {
 	return r.db.Save(document).Error
 }
 
+// UpdateWithVersion snapshots the stored state of a document into a new
+// version and saves the updated document, all in one transaction. The
+// document's Revision must match the stored one, otherwise
+// ErrRevisionConflict is returned. Updating a published document turns it
+// into a draft whose published version is the snapshot. The document's
+// tags, looked up by name, replace the stored ones.
+func (r *DocumentRepository) UpdateWithVersion(document *model.Document, editorID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current model.Document
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Tags").First(&current, document.ID).Error
+		if err != nil {
+			return err
+		}
+		if document.Revision != current.Revision {
+			return ErrRevisionConflict
+		}
+
+		version, err := nextVersion(tx, current.ID)
+		if err != nil {
+			return err
+		}
+
+		snapshot := newVersionSnapshot(&current, version, editorID)
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+
+		// Keep the original creation time and place in the page tree, the
+		// bound document doesn't carry them
+		document.CreatedAt = current.CreatedAt
+		document.SpaceID = current.SpaceID
+		document.ParentID = current.ParentID
+		document.Position = current.Position
+		document.Revision = current.Revision + 1
+		if current.Status == model.StatusPublished {
+			document.Status = model.StatusDraft
+			document.PublishedVersion = &version
+		}
+		if err := tx.Omit("Tags", "Team", "Space", "Category").Save(document).Error; err != nil {
+			return err
+		}
+
+		tags, err := findOrCreateTags(tx, tagNames(document.Tags))
+		if err != nil {
+			return err
+		}
+		document.Tags = tags
+		return tx.Model(document).Association("Tags").Replace(tags)
+	})
+}
+
+// RestoreVersion makes a stored version the new head of a document. The
+// replaced state is recorded as a new version pointing at the restored one.
+// Like an update, restoring turns a published document into a draft.
+func (r *DocumentRepository) RestoreVersion(documentID uint, version int, userID uint) (*model.Document, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var current model.Document
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Tags").First(&current, documentID).Error
+		if err != nil {
+			return err
+		}
+
+		var target model.DocumentVersion
+		err = tx.Where("document_id = ? AND version = ?", documentID, version).First(&target).Error
+		if err != nil {
+			return err
+		}
+
+		next, err := nextVersion(tx, documentID)
+		if err != nil {
+			return err
+		}
+
+		snapshot := newVersionSnapshot(&current, next, userID)
+		snapshot.RestoredFrom = &target.Version
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+
+		current.Title = target.Title
+		current.Description = target.Description
+		current.Content = target.Content
+		current.Type = target.Type
+		current.CategoryID = target.CategoryID
+		current.Category = nil
+		current.ServiceID = target.ServiceID
+		current.Service = nil
+		current.Revision++
+		if current.Status == model.StatusPublished {
+			current.Status = model.StatusDraft
+			current.PublishedVersion = &next
+		}
+		if err := tx.Omit("Tags", "Author", "Service", "Team", "Space", "Category").Save(&current).Error; err != nil {
+			return err
+		}
+
+		tags, err := findOrCreateTags(tx, target.Tags)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&current).Association("Tags").Replace(tags)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(documentID, Viewer{Admin: true})
+}
+
+// GetVersions retrieves all versions of a document, newest first
+func (r *DocumentRepository) GetVersions(documentID uint) ([]model.DocumentVersion, error) {
+	var versions []model.DocumentVersion
+	err := r.db.Where("document_id = ?", documentID).Order("version DESC").Find(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// GetVersion retrieves a single version of a document by its number
+func (r *DocumentRepository) GetVersion(documentID uint, version int) (*model.DocumentVersion, error) {
+	var v model.DocumentVersion
+	err := r.db.Where("document_id = ? AND version = ?", documentID, version).First(&v).Error
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// nextVersion returns the next version number for a document
+func nextVersion(tx *gorm.DB, documentID uint) (int, error) {
+	var last int
+	err := tx.Model(&model.DocumentVersion{}).
+		Where("document_id = ?", documentID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&last).Error
+	if err != nil {
+		return 0, err
+	}
+	return last + 1, nil
+}
+
+// findOrCreateTags looks up tags by name, creating the missing ones. Blank
+// names are skipped and names matching an earlier one regardless of case
+// are folded into it, like the tags table's collation does.
+func findOrCreateTags(tx *gorm.DB, names []string) ([]model.Tag, error) {
+	tags := make([]model.Tag, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		var tag model.Tag
+		if err := tx.Where(model.Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// tagNames returns the names of tags
+func tagNames(tags []model.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// newVersionSnapshot copies the versioned fields of a document
+func newVersionSnapshot(document *model.Document, version int, createdBy uint) *model.DocumentVersion {
+	return &model.DocumentVersion{
+		DocumentID:  document.ID,
+		Version:     version,
+		Title:       document.Title,
+		Description: document.Description,
+		Content:     document.Content,
+		Type:        document.Type,
+		CategoryID:  document.CategoryID,
+		Tags:        tagNames(document.Tags),
+		ServiceID:   document.ServiceID,
+		CreatedBy:   createdBy,
+	}
+}
+
 // Delete deletes a document from the database
 func (r *DocumentRepository) Delete(id uint) error {
 	return r.db.Delete(&model.Document{}, id).Error
 }
 
-// GetByID retrieves a document by its ID
-func (r *DocumentRepository) GetByID(id uint) (*model.Document, error) {
+// GetByID retrieves a document by its ID if the viewer may see it
+func (r *DocumentRepository) GetByID(id uint, viewer Viewer) (*model.Document, error) {
 	logger.Info("Repository: Getting document by ID: %d", id)
 	var document model.Document
-	err := r.db.Preload("Author").Preload("Tags").Preload("Service").First(&document, id).Error
+	err := r.db.Scopes(visibleTo(viewer)).Preload("Author").Preload("Tags").Preload("Service").Preload("Team").Preload("Space").Preload("Category").First(&document, id).Error
 	if err != nil {
 		logger.Error("Repository: Error getting document by ID %d: %v", id, err)
 		return nil, err
 	}
+	if err := r.showPublished(viewer, &document); err != nil {
+		return nil, err
+	}
 	logger.Info("Repository: Successfully retrieved document: %d", id)
 	return &document, nil
 }
 
-// GetAll retrieves all documents
-func (r *DocumentRepository) GetAll() ([]model.Document, error) {
-	var documents []model.Document
-	err := r.db.Preload("Author").Preload("Tags").Preload("Service").Find(&documents).Error
+// IsOpen reports whether a document is open to the viewer regardless of
+// its status, i.e. whether it is unrestricted or the viewer is allowed in
+func (r *DocumentRepository) IsOpen(id uint, viewer Viewer) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Document{}).Scopes(openTo(viewer)).Where("documents.id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// IsVisible reports whether the viewer may see a document. Deleted
+// documents are included, so news of a deletion reaches the same people.
+func (r *DocumentRepository) IsVisible(id uint, viewer Viewer) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&model.Document{}).Scopes(visibleTo(viewer)).Where("documents.id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// IsCurrentVisible reports whether the viewer may see a document as it is
+// stored, which for a published document being redrafted means the draft
+func (r *DocumentRepository) IsCurrentVisible(id uint, viewer Viewer) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&model.Document{}).
+		Scopes(visibleTo(viewer), currentVisibleTo(viewer)).
+		Where("documents.id = ?", id).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// HasDraftAccess reports whether the viewer may see a document's drafts,
+// whatever its status
+func (r *DocumentRepository) HasDraftAccess(id uint, viewer Viewer) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&model.Document{}).
+		Scopes(openTo(viewer), draftAccess(viewer)).
+		Where("documents.id = ?", id).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// documentSorts are the fields a document list may be sorted by
+var documentSorts = []string{"id", "title", "created_at", "updated_at"}
+
+// List retrieves one page of the documents the viewer may see, narrowed by
+// the query's filters
+func (r *DocumentRepository) List(viewer Viewer, q ListQuery) (*Page[model.Document], error) {
+	db := r.db.Scopes(visibleTo(viewer), documentFilters(q.Filter))
+	page, err := paginate[model.Document](db, "documents", q, documentSorts, "-updated_at", "Author", "Tags", "Service", "Team", "Category")
 	if err != nil {
 		return nil, err
 	}
-	return documents, nil
+
+	documents := make([]*model.Document, len(page.Items))
+	for i := range page.Items {
+		documents[i] = &page.Items[i]
+	}
+	if err := r.showPublished(viewer, documents...); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// documentFilters applies the list filters a document query supports
+func documentFilters(f ListFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Scopes(ownedByTeam(f.TeamID), createdBetween("documents", f), updatedBetween("documents", f))
+		if f.Type != "" {
+			db = db.Where("documents.type = ?", f.Type)
+		}
+		if f.Category != "" {
+			db = db.Where("documents.category_id IN (?)", categoryIDs(db, f.Category))
+		}
+		if f.ServiceID != nil {
+			db = db.Where("documents.service_id = ?", *f.ServiceID)
+		}
+		if f.AuthorID != nil {
+			db = db.Where("documents.author_id = ?", *f.AuthorID)
+		}
+		if f.Tag != "" {
+			db = db.Where("EXISTS (SELECT 1 FROM document_tags dt JOIN tags t ON t.id = dt.tag_id "+
+				"WHERE dt.document_id = documents.id AND t.name = ? AND t.deleted_at IS NULL)", f.Tag)
+		}
+		if f.Status != "" {
+			db = db.Where("documents.status = ?", f.Status)
+		}
+		if f.SpaceID != nil {
+			db = db.Where("documents.space_id = ?", *f.SpaceID)
+		}
+		return db
+	}
 }
 
-// GetByAuthor retrieves all documents by an author ID
-func (r *DocumentRepository) GetByAuthor(authorID uint) ([]model.Document, error) {
+func (r *DocumentRepository) GetByAuthorID(authorID uint) ([]model.Document, error) {
 	var documents []model.Document
-	err := r.db.Where("author_id = ?", authorID).Preload("Author").Preload("Tags").Find(&documents).Error
+	err := r.db.Where("author_id = ?", authorID).Preload("Author").Preload("Tags").Preload("Service").Preload("Category").Find(&documents).Error
 	if err != nil {
 		return nil, err
 	}
 	return documents, nil
 }
 
-// GetByCategory retrieves all documents by category
-func (r *DocumentRepository) GetByCategory(category string) ([]model.Document, error) {
-	var documents []model.Document
-	err := r.db.Where("category = ?", category).Preload("Author").Preload("Tags").Find(&documents).Error
+// UpdateStatus moves a document from one status to another and sets its
+// published version, bumping the revision. ErrRevisionConflict is returned
+// when the document isn't in the expected status anymore.
+func (r *DocumentRepository) UpdateStatus(id uint, from, to string, publishedVersion *int) error {
+	result := r.db.Model(&model.Document{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(map[string]interface{}{
+			"status":            to,
+			"published_version": publishedVersion,
+			"revision":          gorm.Expr("revision + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRevisionConflict
+	}
+	return nil
+}
+
+// showPublished replaces the drafts of published documents with their
+// published version, unless the viewer may see the drafts
+func (r *DocumentRepository) showPublished(viewer Viewer, documents ...*model.Document) error {
+	if viewer.Admin {
+		return nil
+	}
+
+	var ids []uint
+	for _, doc := range documents {
+		if hasHiddenDraft(doc) {
+			ids = append(ids, doc.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var drafters []uint
+	err := r.db.Model(&model.Document{}).Scopes(draftAccess(viewer)).Where("documents.id IN ?", ids).Pluck("documents.id", &drafters).Error
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return documents, nil
+	mayEdit := make(map[uint]bool, len(drafters))
+	for _, id := range drafters {
+		mayEdit[id] = true
+	}
+
+	for _, doc := range documents {
+		if !hasHiddenDraft(doc) || mayEdit[doc.ID] {
+			continue
+		}
+		published, err := r.GetVersion(doc.ID, *doc.PublishedVersion)
+		if err != nil {
+			return err
+		}
+		var tags []model.Tag
+		if len(published.Tags) > 0 {
+			if err := r.db.Where("name IN ?", []string(published.Tags)).Find(&tags).Error; err != nil {
+				return err
+			}
+		}
+
+		doc.Title = published.Title
+		doc.Description = published.Description
+		doc.Content = published.Content
+		doc.Type = published.Type
+		if doc.Category != nil && !equalIDs(published.CategoryID, &doc.Category.ID) {
+			doc.Category = nil
+		}
+		doc.CategoryID = published.CategoryID
+		doc.Tags = tags
+		if doc.Service != nil && (published.ServiceID == nil || *published.ServiceID != doc.Service.ID) {
+			doc.Service = nil
+		}
+		doc.ServiceID = published.ServiceID
+		doc.Status = model.StatusPublished
+	}
+	return nil
 }
 
-func (r *DocumentRepository) GetByAuthorID(authorID uint) ([]model.Document, error) {
+// hasHiddenDraft reports whether a document is a draft with a published
+// version that readers see instead
+func hasHiddenDraft(doc *model.Document) bool {
+	return doc.PublishedVersion != nil && doc.Status != model.StatusPublished && doc.Status != model.StatusArchived
+}
+
+// ErrPageCycle is returned when a page would be moved below itself or one
+// of its subpages
+var ErrPageCycle = errors.New("a page can't be moved below itself or its subpages")
+
+// treeColumns are the columns loaded for pages shown in a tree or trail,
+// including those showPublished needs
+var treeColumns = []string{
+	"id", "title", "status", "published_version", "author_id", "team_id", "restricted",
+	"space_id", "parent_id", "position",
+}
+
+// ListInSpace retrieves the pages of a space the viewer may see, with just
+// what is needed to draw its tree, ordered by position
+func (r *DocumentRepository) ListInSpace(spaceID uint, viewer Viewer) ([]model.Document, error) {
 	var documents []model.Document
-	err := r.db.Where("author_id = ?", authorID).Preload("Author").Preload("Tags").Preload("Service").Find(&documents).Error
+	err := r.db.Scopes(visibleTo(viewer)).Select(treeColumns).
+		Where("documents.space_id = ?", spaceID).
+		Order("documents.position").Order("documents.id").
+		Find(&documents).Error
 	if err != nil {
 		return nil, err
 	}
+
+	pages := make([]*model.Document, len(documents))
+	for i := range documents {
+		pages[i] = &documents[i]
+	}
+	if err := r.showPublished(viewer, pages...); err != nil {
+		return nil, err
+	}
 	return documents, nil
 }
+
+// Ancestors retrieves the pages above a document, from the top of its tree
+// down to its parent. The trail stops below the first page the viewer may
+// not see.
+func (r *DocumentRepository) Ancestors(doc *model.Document, viewer Viewer) ([]model.Document, error) {
+	var ancestors []model.Document
+	seen := map[uint]bool{doc.ID: true}
+	for parentID := doc.ParentID; parentID != nil && !seen[*parentID]; {
+		seen[*parentID] = true
+		var parent model.Document
+		err := r.db.Scopes(visibleTo(viewer)).Select(treeColumns).First(&parent, *parentID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, parent)
+		parentID = parent.ParentID
+	}
+
+	trail := make([]model.Document, len(ancestors))
+	pages := make([]*model.Document, len(ancestors))
+	for i := range ancestors {
+		trail[i] = ancestors[len(ancestors)-1-i]
+		pages[i] = &trail[i]
+	}
+	if err := r.showPublished(viewer, pages...); err != nil {
+		return nil, err
+	}
+	return trail, nil
+}
+
+// NextPosition returns the position after the last page below a parent, or
+// at the top of a space when parentID is nil. Pages outside of any space
+// aren't ordered.
+func (r *DocumentRepository) NextPosition(spaceID, parentID *uint) (int, error) {
+	if spaceID == nil && parentID == nil {
+		return 0, nil
+	}
+	var last sql.NullInt64
+	err := r.db.Model(&model.Document{}).Scopes(siblingsOf(spaceID, parentID)).
+		Select("MAX(position)").Row().Scan(&last)
+	if err != nil || !last.Valid {
+		return 0, err
+	}
+	return int(last.Int64) + 1, nil
+}
+
+// Move places a document in a space, below a parent page and at a position
+// among its siblings, shifting the other pages to make room. A nil
+// position puts it last. Its subpages follow it into the new space.
+// ErrPageCycle is returned when the parent is the document or lies below it.
+func (r *DocumentRepository) Move(id uint, spaceID, parentID *uint, position *int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var doc model.Document
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&doc, id).Error; err != nil {
+			return err
+		}
+
+		// Walk up from the new parent, through deleted pages too since they
+		// may come back from the trash
+		seen := make(map[uint]bool)
+		for p := parentID; p != nil && !seen[*p]; {
+			if *p == id {
+				return ErrPageCycle
+			}
+			seen[*p] = true
+			var parent model.Document
+			if err := tx.Unscoped().Select("id", "parent_id").First(&parent, *p).Error; err != nil {
+				return err
+			}
+			p = parent.ParentID
+		}
+
+		at := 0
+		if spaceID != nil || parentID != nil {
+			var siblings []model.Document
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "position").
+				Scopes(siblingsOf(spaceID, parentID)).Where("id <> ?", id).
+				Order("position").Order("id").Find(&siblings).Error
+			if err != nil {
+				return err
+			}
+			at = len(siblings)
+			if position != nil && *position < at {
+				at = *position
+			}
+			for i, sibling := range siblings {
+				want := i
+				if i >= at {
+					want++
+				}
+				if sibling.Position == want {
+					continue
+				}
+				if err := tx.Model(&sibling).UpdateColumn("position", want).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		err := tx.Model(&doc).UpdateColumns(map[string]interface{}{
+			"space_id":  spaceID,
+			"parent_id": parentID,
+			"position":  at,
+		}).Error
+		if err != nil || equalIDs(spaceID, doc.SpaceID) {
+			return err
+		}
+
+		// Subpages, deleted ones included, move to the new space as well
+		moved := map[uint]bool{id: true}
+		for level := []uint{id}; len(level) > 0; {
+			var children []uint
+			err := tx.Unscoped().Model(&model.Document{}).Where("parent_id IN ?", level).Pluck("id", &children).Error
+			if err != nil {
+				return err
+			}
+			level = level[:0]
+			for _, child := range children {
+				if !moved[child] {
+					moved[child] = true
+					level = append(level, child)
+				}
+			}
+			if len(level) == 0 {
+				break
+			}
+			err = tx.Unscoped().Model(&model.Document{}).Where("id IN ?", level).UpdateColumn("space_id", spaceID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// siblingsOf limits a document query to the pages right below a parent, or
+// at the top of a space when parentID is nil
+func siblingsOf(spaceID, parentID *uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if parentID != nil {
+			return db.Where("parent_id = ?", *parentID)
+		}
+		if spaceID == nil {
+			return db.Where("parent_id IS NULL AND space_id IS NULL")
+		}
+		return db.Where("parent_id IS NULL AND space_id = ?", *spaceID)
+	}
+}
+
+func equalIDs(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
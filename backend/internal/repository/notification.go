@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"techdocs/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Create stores new notifications
+func (r *NotificationRepository) Create(notifications []model.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return r.db.Omit(clause.Associations).Create(&notifications).Error
+}
+
+// notificationSorts are the fields a notification list may be sorted by
+var notificationSorts = []string{"id", "created_at"}
+
+// List retrieves one page of a user's notifications, newest first unless
+// sorted otherwise
+func (r *NotificationRepository) List(userID uint, q ListQuery) (*Page[model.Notification], error) {
+	db := r.db.Scopes(createdBetween("notifications", q.Filter)).Where("notifications.user_id = ?", userID)
+	if q.Filter.Unread {
+		db = db.Where("notifications.read_at IS NULL")
+	}
+	return paginate[model.Notification](db, "notifications", q, notificationSorts, "-created_at", "Actor")
+}
+
+// CountUnread counts a user's unread notifications
+func (r *NotificationRepository) CountUnread(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks one of a user's notifications as read. A notification
+// that was read before keeps its original read time.
+func (r *NotificationRepository) MarkRead(userID, id uint) error {
+	var notification model.Notification
+	if err := r.db.Where("user_id = ?", userID).First(&notification, id).Error; err != nil {
+		return err
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return r.db.Model(&notification).Update("read_at", time.Now()).Error
+}
+
+// MarkAllRead marks all of a user's notifications as read and returns how
+// many were unread
+func (r *NotificationRepository) MarkAllRead(userID uint) (int64, error) {
+	result := r.db.Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// GetUnemailed retrieves the notifications created since a point in time
+// that haven't been e-mailed yet to users with the given digest
+// preference, skipping those that failed too often
+func (r *NotificationRepository) GetUnemailed(digest string, since time.Time, maxAttempts int) ([]model.Notification, error) {
+	var notifications []model.Notification
+	err := r.db.Joins("JOIN users ON users.id = notifications.user_id AND users.deleted_at IS NULL").
+		Where("users.digest = ?", digest).
+		Where("notifications.emailed_at IS NULL AND notifications.read_at IS NULL").
+		Where("notifications.created_at >= ? AND notifications.email_attempts < ?", since, maxAttempts).
+		Preload("User").Preload("Actor").
+		Order("notifications.user_id, notifications.created_at").
+		Find(&notifications).Error
+	return notifications, err
+}
+
+// MarkEmailed records that notifications were delivered by e-mail
+func (r *NotificationRepository) MarkEmailed(ids []uint) error {
+	return r.db.Model(&model.Notification{}).Where("id IN ?", ids).Update("emailed_at", time.Now()).Error
+}
+
+// RecordEmailFailure counts a failed delivery attempt of notifications
+func (r *NotificationRepository) RecordEmailFailure(ids []uint) error {
+	return r.db.Model(&model.Notification{}).Where("id IN ?", ids).
+		Update("email_attempts", gorm.Expr("email_attempts + 1")).Error
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"techdocs/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type PermissionRepository struct {
+	db *gorm.DB
+}
+
+func NewPermissionRepository(db *gorm.DB) *PermissionRepository {
+	return &PermissionRepository{db: db}
+}
+
+// GetLevels returns the permission levels a user holds on a document,
+// granted directly or through one of their teams
+func (r *PermissionRepository) GetLevels(documentID, userID uint) ([]string, error) {
+	var levels []string
+	err := r.db.Model(&model.DocumentPermission{}).
+		Where("document_id = ?", documentID).
+		Where("user_id = ? OR team_id IN (?)", userID, memberTeams(r.db, userID)).
+		Pluck("level", &levels).Error
+	if err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// GetByDocument retrieves all grants on a document
+func (r *PermissionRepository) GetByDocument(documentID uint) ([]model.DocumentPermission, error) {
+	var permissions []model.DocumentPermission
+	err := r.db.Where("document_id = ?", documentID).Preload("User").Preload("Team").Find(&permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// Grant creates or updates the grant of a user or team on a document
+func (r *PermissionRepository) Grant(permission *model.DocumentPermission) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.DocumentPermission
+		err := tx.Scopes(principal(permission.DocumentID, permission.UserID, permission.TeamID)).
+			Limit(1).Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			permission.ID = existing.ID
+			permission.CreatedAt = existing.CreatedAt
+		}
+		return tx.Omit("User", "Team").Save(permission).Error
+	})
+}
+
+// Revoke removes the grant of a user or team on a document
+func (r *PermissionRepository) Revoke(documentID uint, userID, teamID *uint) error {
+	return r.db.Unscoped().
+		Scopes(principal(documentID, userID, teamID)).
+		Delete(&model.DocumentPermission{}).Error
+}
+
+// principal selects the grant on a document held by a user or by a team
+func principal(documentID uint, userID, teamID *uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("document_id = ?", documentID)
+		if userID != nil {
+			return db.Where("user_id = ?", *userID)
+		}
+		return db.Where("team_id = ?", teamID)
+	}
+}
+
+// memberTeams is a subquery for the IDs of the teams a user belongs to
+func memberTeams(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&model.TeamMember{}).Select("team_id").Where("user_id = ?", userID)
+}
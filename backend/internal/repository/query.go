@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// List page sizes
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var (
+	// ErrInvalidSort is returned when a list is sorted by an unknown field
+	ErrInvalidSort = errors.New("invalid sort field")
+	// ErrInvalidCursor is returned when a cursor can't be decoded
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// ListQuery selects one page of a list. Sort names a field, prefixed with
+// "-" for descending order. When Cursor is set it takes precedence over
+// Page.
+type ListQuery struct {
+	Page   int
+	Limit  int
+	Cursor string
+	Sort   string
+	Filter ListFilter
+}
+
+// ListFilter narrows a list down. Each repository applies the filters that
+// make sense for its resource and ignores the others.
+type ListFilter struct {
+	Type          string
+	Category      string
+	Tag           string
+	Role          string
+	Status        string
+	Resolved      *bool
+	Unread        bool
+	ServiceID     *uint
+	SpaceID       *uint
+	AuthorID      *uint
+	TeamID        *uint
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+}
+
+// Page is one page of a list along with the total number of matching rows
+// and the cursor of the next page, if there is one
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor marks the last row of a page by its sort value and ID
+type cursor struct {
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+var schemaCache sync.Map
+
+// paginate runs a filtered query for one page of T. sortable lists the
+// columns the list may be sorted by and defaultSort is used when the query
+// doesn't name one. Rows are always ordered by ID last, so pages are stable
+// and cursors unambiguous.
+func paginate[T any](db *gorm.DB, table string, q ListQuery, sortable []string, defaultSort string, preloads ...string) (*Page[T], error) {
+	sch, err := schema.Parse(new(T), &schemaCache, db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	sort := q.Sort
+	if sort == "" {
+		sort = defaultSort
+	}
+	desc := strings.HasPrefix(sort, "-")
+	sort = strings.TrimPrefix(sort, "-")
+	if !contains(sortable, sort) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSort, sort)
+	}
+	field := sch.LookUpField(sort)
+	if field == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSort, sort)
+	}
+
+	limit := q.Limit
+	if limit < 1 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	page := &Page[T]{Items: []T{}, Limit: limit}
+
+	db = db.Model(new(T)).Session(&gorm.Session{})
+	if err := db.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	column := table + "." + field.DBName
+	id := table + ".id"
+	order, op := "ASC", ">"
+	if desc {
+		order, op = "DESC", "<"
+	}
+
+	tx := db.Order(column + " " + order).Order(id + " " + order).Limit(limit + 1)
+	if q.Cursor != "" {
+		value, lastID, err := decodeCursor(q.Cursor, field)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", column, op, column, id, op), value, value, lastID)
+	} else {
+		page.Page = q.Page
+		if page.Page < 1 {
+			page.Page = 1
+		}
+		tx = tx.Offset((page.Page - 1) * limit)
+	}
+	for _, p := range preloads {
+		tx = tx.Preload(p)
+	}
+
+	if err := tx.Find(&page.Items).Error; err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		next, err := encodeCursor(sch, field, &page.Items[limit-1])
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = next
+	}
+	return page, nil
+}
+
+func encodeCursor(sch *schema.Schema, field *schema.Field, row interface{}) (string, error) {
+	v := reflect.ValueOf(row).Elem()
+	ctx := context.Background()
+
+	value, _ := field.ValueOf(ctx, v)
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	id, _ := sch.PrioritizedPrimaryField.ValueOf(ctx, v)
+	idValue, ok := id.(uint)
+	if !ok {
+		return "", fmt.Errorf("unsupported primary key type %T", id)
+	}
+
+	data, err := json.Marshal(cursor{Value: raw, ID: idValue})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns the sort value and ID a cursor points past, with
+// the value typed like the sort field so it binds as a proper parameter
+func decodeCursor(encoded string, field *schema.Field) (interface{}, uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Value == nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	value := reflect.New(field.IndirectFieldType)
+	if err := json.Unmarshal(c.Value, value.Interface()); err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	return value.Elem().Interface(), c.ID, nil
+}
+
+// createdBetween and updatedBetween apply the date range filters
+func createdBetween(table string, f ListFilter) func(*gorm.DB) *gorm.DB {
+	return between(table+".created_at", f.CreatedAfter, f.CreatedBefore)
+}
+
+func updatedBetween(table string, f ListFilter) func(*gorm.DB) *gorm.DB {
+	return between(table+".updated_at", f.UpdatedAfter, f.UpdatedBefore)
+}
+
+func between(column string, after, before *time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if after != nil {
+			db = db.Where(column+" >= ?", *after)
+		}
+		if before != nil {
+			db = db.Where(column+" < ?", *before)
+		}
+		return db
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
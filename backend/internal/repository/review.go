@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"techdocs/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+// GetReviewers retrieves the reviewers of a document with their users
+func (r *ReviewRepository) GetReviewers(documentID uint) ([]model.DocumentReviewer, error) {
+	var reviewers []model.DocumentReviewer
+	err := r.db.Where("document_id = ?", documentID).Preload("User").Order("id").Find(&reviewers).Error
+	return reviewers, err
+}
+
+// GetReviewer retrieves a user's review of a document
+func (r *ReviewRepository) GetReviewer(documentID, userID uint) (*model.DocumentReviewer, error) {
+	var reviewer model.DocumentReviewer
+	err := r.db.Where("document_id = ? AND user_id = ?", documentID, userID).First(&reviewer).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reviewer, nil
+}
+
+// ReplaceReviewers makes the given users the reviewers of a document.
+// Reviewers who stay keep their decision, the others are removed for good
+// so they can be assigned again later.
+func (r *ReviewRepository) ReplaceReviewers(documentID uint, userIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		removed := tx.Unscoped().Where("document_id = ?", documentID)
+		if len(userIDs) > 0 {
+			removed = removed.Where("user_id NOT IN ?", userIDs)
+		}
+		if err := removed.Delete(&model.DocumentReviewer{}).Error; err != nil {
+			return err
+		}
+
+		for _, userID := range userIDs {
+			reviewer := model.DocumentReviewer{DocumentID: documentID, UserID: userID}
+			err := tx.Where(reviewer).Attrs(model.DocumentReviewer{Decision: model.ReviewPending}).
+				FirstOrCreate(&reviewer).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ResetDecisions sets every review of a document back to pending, for a
+// new submission
+func (r *ReviewRepository) ResetDecisions(documentID uint) error {
+	return r.db.Model(&model.DocumentReviewer{}).
+		Where("document_id = ?", documentID).
+		Updates(map[string]interface{}{"decision": model.ReviewPending, "comment": "", "decided_at": nil}).Error
+}
+
+// SetDecision records a reviewer's decision on a document
+func (r *ReviewRepository) SetDecision(reviewer *model.DocumentReviewer, decision, comment string) error {
+	now := time.Now()
+	reviewer.Decision = decision
+	reviewer.Comment = comment
+	reviewer.DecidedAt = &now
+	return r.db.Model(reviewer).Select("Decision", "Comment", "DecidedAt").Updates(reviewer).Error
+}
+
+// ListPending retrieves one page of the documents in review that wait for
+// a user's decision
+func (r *ReviewRepository) ListPending(userID uint, q ListQuery) (*Page[model.Document], error) {
+	db := r.db.Where("documents.status = ?", model.StatusInReview).
+		Where("EXISTS (SELECT 1 FROM document_reviewers dr WHERE dr.document_id = documents.id "+
+			"AND dr.user_id = ? AND dr.decision IN ? AND dr.deleted_at IS NULL)",
+			userID, []string{model.ReviewPending, model.ReviewCommented})
+	return paginate[model.Document](db, "documents", q, documentSorts, "updated_at", "Author", "Tags", "Service", "Team", "Category")
+}
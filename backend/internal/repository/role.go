@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"techdocs/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// Create creates a new role with its permissions
+func (r *RoleRepository) Create(role *model.Role) error {
+	return r.db.Omit("Permissions.*").Create(role).Error
+}
+
+// Update updates a role's description and replaces its permissions
+func (r *RoleRepository) Update(role *model.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Select("Description").Updates(role).Error; err != nil {
+			return err
+		}
+		return tx.Model(role).Association("Permissions").Replace(role.Permissions)
+	})
+}
+
+// Delete deletes a role for good, freeing its name
+func (r *RoleRepository) Delete(role *model.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(role).Error
+	})
+}
+
+// GetByID retrieves a role with its permissions
+func (r *RoleRepository) GetByID(id uint) (*model.Role, error) {
+	var role model.Role
+	err := r.db.Preload("Permissions").First(&role, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetByName retrieves a role with its permissions by name
+func (r *RoleRepository) GetByName(name string) (*model.Role, error) {
+	var role model.Role
+	err := r.db.Preload("Permissions").Where("name = ?", name).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetAll retrieves all roles with their permissions
+func (r *RoleRepository) GetAll() ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.Preload("Permissions").Order("name").Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// Taken reports whether a role already uses a name
+func (r *RoleRepository) Taken(name string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Role{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+// CountUsers counts the users holding a role
+func (r *RoleRepository) CountUsers(name string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.User{}).Where("role = ?", name).Count(&count).Error
+	return count, err
+}
+
+// GetPermissions retrieves all known permissions
+func (r *RoleRepository) GetPermissions() ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := r.db.Order("name").Find(&permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// GetPermissionsByName retrieves the permissions with the given names
+func (r *RoleRepository) GetPermissionsByName(names []string) ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := r.db.Where("name IN ?", names).Find(&permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// GetUserPermissions returns the names of the permissions granted to a user
+// through their role
+func (r *RoleRepository) GetUserPermissions(userID uint) ([]string, error) {
+	var names []string
+	err := r.db.Model(&model.Permission{}).
+		Joins("JOIN role_permissions rp ON rp.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = rp.role_id AND roles.deleted_at IS NULL").
+		Joins("JOIN users ON users.role = roles.name AND users.deleted_at IS NULL").
+		Where("users.id = ?", userID).
+		Pluck("permissions.name", &names).Error
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
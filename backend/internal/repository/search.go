@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"strings"
+	"techdocs/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// tagMatchScore is the relevance added to a document for each of its tags
+// matching the query, on the same scale as MySQL full-text scores
+const tagMatchScore = 1.0
+
+// SearchHit is a matching row with its relevance score
+type SearchHit struct {
+	ID    uint
+	Score float64
+}
+
+// SearchRepository runs full-text queries. Documents, services and
+// comments carry MySQL FULLTEXT indexes, tags are matched by name prefix.
+type SearchRepository struct {
+	db        *gorm.DB
+	documents *DocumentRepository
+}
+
+func NewSearchRepository(db *gorm.DB) *SearchRepository {
+	return &SearchRepository{db: db, documents: NewDocumentRepository(db)}
+}
+
+// SearchDocuments ranks the documents the viewer may see by how well their
+// title, description, content and tags match the query. Documents being
+// redrafted are matched against the published version for readers who
+// only see that one.
+func (r *SearchRepository) SearchDocuments(query string, viewer Viewer, limit int) ([]SearchHit, error) {
+	current := func() *gorm.DB {
+		return r.db.Model(&model.Document{}).Scopes(visibleTo(viewer), currentVisibleTo(viewer))
+	}
+	textHits, err := matchText(current(), "title, description, content", query, limit)
+	if err != nil {
+		return nil, err
+	}
+	tagHits, err := matchTags(current().
+		Joins("JOIN document_tags dt ON dt.document_id = documents.id").
+		Joins("JOIN tags ON tags.id = dt.tag_id AND tags.deleted_at IS NULL"), query, limit)
+	if err != nil {
+		return nil, err
+	}
+	if viewer.Admin {
+		return mergeHits(textHits, tagHits), nil
+	}
+
+	published := func() *gorm.DB {
+		return r.db.Model(&model.Document{}).
+			Scopes(visibleTo(viewer), publishedOnlyTo(viewer)).
+			Joins("JOIN document_versions dv ON dv.document_id = documents.id " +
+				"AND dv.version = documents.published_version AND dv.deleted_at IS NULL")
+	}
+	publishedTextHits, err := matchText(published(), "dv.title, dv.description, dv.content", query, limit)
+	if err != nil {
+		return nil, err
+	}
+	publishedTagHits, err := matchTags(published().
+		Joins("JOIN tags ON JSON_CONTAINS(dv.tags, JSON_QUOTE(tags.name)) AND tags.deleted_at IS NULL"), query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeHits(textHits, tagHits, publishedTextHits, publishedTagHits), nil
+}
+
+// matchText ranks the documents of a query by a full-text match of the
+// query against columns
+func matchText(db *gorm.DB, columns, query string, limit int) ([]SearchHit, error) {
+	var hits []SearchHit
+	match := "MATCH(" + columns + ") AGAINST (?)"
+	err := db.Select("documents.id, "+match+" AS score", query).
+		Where(match, query).
+		Order("score DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// matchTags ranks the documents of a query joined with their tags by how
+// many of the tags start with the query
+func matchTags(db *gorm.DB, query string, limit int) ([]SearchHit, error) {
+	var hits []SearchHit
+	err := db.Select("documents.id, COUNT(*) * ? AS score", tagMatchScore).
+		Where("tags.name LIKE ?", likePrefix(query)).
+		Group("documents.id").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// SearchServices ranks services by how well their name matches the query
+func (r *SearchRepository) SearchServices(query string, limit int) ([]SearchHit, error) {
+	var hits []SearchHit
+	err := r.db.Model(&model.Service{}).
+		Select("id, MATCH(name) AGAINST (?) AS score", query).
+		Where("MATCH(name) AGAINST (?)", query).
+		Order("score DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// SearchComments ranks comments on documents the viewer may see by how
+// well their content matches the query
+func (r *SearchRepository) SearchComments(query string, viewer Viewer, limit int) ([]SearchHit, error) {
+	var hits []SearchHit
+	err := r.db.Model(&model.Comment{}).
+		Joins("JOIN documents ON documents.id = comments.document_id AND documents.deleted_at IS NULL").
+		Scopes(visibleTo(viewer)).
+		Select("comments.id, MATCH(comments.content) AGAINST (?) AS score", query).
+		Where("MATCH(comments.content) AGAINST (?)", query).
+		Order("score DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// GetDocuments loads documents by ID with their tags, service and
+// category, as the viewer sees them
+func (r *SearchRepository) GetDocuments(ids []uint, viewer Viewer) ([]model.Document, error) {
+	var documents []model.Document
+	if len(ids) == 0 {
+		return documents, nil
+	}
+	err := r.db.Preload("Tags").Preload("Service").Preload("Category").Find(&documents, ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	shown := make([]*model.Document, len(documents))
+	for i := range documents {
+		shown[i] = &documents[i]
+	}
+	if err := r.documents.showPublished(viewer, shown...); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// GetServices loads services by ID
+func (r *SearchRepository) GetServices(ids []uint) ([]model.Service, error) {
+	var services []model.Service
+	if len(ids) == 0 {
+		return services, nil
+	}
+	err := r.db.Find(&services, ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// GetComments loads comments by ID with their document, as the viewer
+// sees it
+func (r *SearchRepository) GetComments(ids []uint, viewer Viewer) ([]model.Comment, error) {
+	var comments []model.Comment
+	if len(ids) == 0 {
+		return comments, nil
+	}
+	err := r.db.Preload("Document").Find(&comments, ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	shown := make([]*model.Document, len(comments))
+	for i := range comments {
+		shown[i] = &comments[i].Document
+	}
+	if err := r.documents.showPublished(viewer, shown...); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// mergeHits adds up the scores of hits on the same row
+func mergeHits(lists ...[]SearchHit) []SearchHit {
+	index := make(map[uint]int)
+	var merged []SearchHit
+	for _, list := range lists {
+		for _, hit := range list {
+			if i, ok := index[hit.ID]; ok {
+				merged[i].Score += hit.Score
+				continue
+			}
+			index[hit.ID] = len(merged)
+			merged = append(merged, hit)
+		}
+	}
+	return merged
+}
+
+// likePrefix turns s into a LIKE pattern matching values starting with s
+func likePrefix(s string) string {
+	return likeEscape(s) + "%"
+}
+
+// likeEscape escapes the LIKE wildcards in s so they match literally
+func likeEscape(s string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return escaper.Replace(s)
+}
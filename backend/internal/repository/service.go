@@ -4,6 +4,7 @@ import (
 	"techdocs/internal/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ServiceRepository struct {
@@ -16,12 +17,27 @@ func NewServiceRepository(db *gorm.DB) *ServiceRepository {
 
 // Create creates a new service in the database
 func (r *ServiceRepository) Create(service *model.Service) error {
-	return r.db.Create(service).Error
+	return r.db.Omit("Team", "Category").Create(service).Error
 }
 
-// Update updates an existing service in the database
+// Update updates an existing service in the database. The service's
+// Revision must match the stored one, otherwise ErrRevisionConflict is
+// returned.
 func (r *ServiceRepository) Update(service *model.Service) error {
-	return r.db.Save(service).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current model.Service
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, service.ID).Error
+		if err != nil {
+			return err
+		}
+		if service.Revision != current.Revision {
+			return ErrRevisionConflict
+		}
+
+		service.CreatedAt = current.CreatedAt
+		service.Revision = current.Revision + 1
+		return tx.Omit("Team", "Category").Save(service).Error
+	})
 }
 
 // Delete deletes a service from the database
@@ -32,29 +48,22 @@ func (r *ServiceRepository) Delete(id uint) error {
 // GetByID retrieves a service by its ID
 func (r *ServiceRepository) GetByID(id uint) (*model.Service, error) {
 	var service model.Service
-	err := r.db.First(&service, id).Error
+	err := r.db.Preload("Team").Preload("Category").First(&service, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &service, nil
 }
 
-// GetAll retrieves all services
-func (r *ServiceRepository) GetAll() ([]model.Service, error) {
-	var services []model.Service
-	err := r.db.Find(&services).Error
-	if err != nil {
-		return nil, err
-	}
-	return services, nil
-}
+// serviceSorts are the fields a service list may be sorted by
+var serviceSorts = []string{"id", "name", "created_at", "updated_at"}
 
-// GetByCategory retrieves all services by category
-func (r *ServiceRepository) GetByCategory(category string) ([]model.Service, error) {
-	var services []model.Service
-	err := r.db.Where("category = ?", category).Find(&services).Error
-	if err != nil {
-		return nil, err
+// List retrieves one page of services, narrowed by the query's filters
+func (r *ServiceRepository) List(q ListQuery) (*Page[model.Service], error) {
+	f := q.Filter
+	db := r.db.Scopes(ownedByTeam(f.TeamID), createdBetween("services", f), updatedBetween("services", f))
+	if f.Category != "" {
+		db = db.Where("services.category_id IN (?)", categoryIDs(db, f.Category))
 	}
-	return services, nil
+	return paginate[model.Service](db, "services", q, serviceSorts, "name", "Team", "Category")
 }
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"techdocs/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type SpaceRepository struct {
+	db *gorm.DB
+}
+
+func NewSpaceRepository(db *gorm.DB) *SpaceRepository {
+	return &SpaceRepository{db: db}
+}
+
+// Create creates a new space
+func (r *SpaceRepository) Create(space *model.Space) error {
+	return r.db.Omit("Team").Create(space).Error
+}
+
+// Update updates a space's name, description and owning team
+func (r *SpaceRepository) Update(space *model.Space) error {
+	return r.db.Model(space).Select("Name", "Description", "TeamID").Updates(space).Error
+}
+
+// Delete deletes a space for good, freeing its name. Its pages, deleted
+// ones included, keep their tree but no longer belong to a space.
+func (r *SpaceRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&model.Document{}).Where("space_id = ?", id).UpdateColumn("space_id", nil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&model.Space{}, id).Error
+	})
+}
+
+// Taken reports whether a space other than exceptID already uses a name
+func (r *SpaceRepository) Taken(name string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Space{}).Where("name = ? AND id <> ?", name, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// GetByID retrieves a space by its ID
+func (r *SpaceRepository) GetByID(id uint) (*model.Space, error) {
+	var space model.Space
+	err := r.db.Preload("Team").First(&space, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &space, nil
+}
+
+// spaceSorts are the fields a space list may be sorted by
+var spaceSorts = []string{"id", "name", "created_at", "updated_at"}
+
+// List retrieves one page of spaces, narrowed by the query's filters
+func (r *SpaceRepository) List(q ListQuery) (*Page[model.Space], error) {
+	f := q.Filter
+	db := r.db.Scopes(ownedByTeam(f.TeamID), createdBetween("spaces", f), updatedBetween("spaces", f))
+	return paginate[model.Space](db, "spaces", q, spaceSorts, "name", "Team")
+}
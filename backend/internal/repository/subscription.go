@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"techdocs/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type SubscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionRepository(db *gorm.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+// FindOrCreate subscribes a user to a target, returning the existing
+// subscription if there is one
+func (r *SubscriptionRepository) FindOrCreate(subscription *model.Subscription) error {
+	return r.db.Where(model.Subscription{
+		UserID:     subscription.UserID,
+		TargetType: subscription.TargetType,
+		TargetID:   subscription.TargetID,
+	}).FirstOrCreate(subscription).Error
+}
+
+// Delete removes one of a user's subscriptions. Subscriptions are deleted
+// for good so the user can subscribe to the same target again.
+func (r *SubscriptionRepository) Delete(userID, id uint) error {
+	result := r.db.Unscoped().Where("user_id = ?", userID).Delete(&model.Subscription{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// subscriptionSorts are the fields a subscription list may be sorted by
+var subscriptionSorts = []string{"id", "created_at"}
+
+// List retrieves one page of a user's subscriptions
+func (r *SubscriptionRepository) List(userID uint, q ListQuery) (*Page[model.Subscription], error) {
+	db := r.db.Where("subscriptions.user_id = ?", userID)
+	if q.Filter.Type != "" {
+		db = db.Where("subscriptions.target_type = ?", q.Filter.Type)
+	}
+	return paginate[model.Subscription](db, "subscriptions", q, subscriptionSorts, "-created_at")
+}
+
+// GetSubscribers returns the IDs of the users subscribed to a document,
+// to its service or to any of the named tags
+func (r *SubscriptionRepository) GetSubscribers(documentID uint, serviceID *uint, tags []string) ([]uint, error) {
+	targets := r.db.Where("target_type = ? AND target_id = ?", model.SubscriptionDocument, documentID)
+	if serviceID != nil {
+		targets = targets.Or("target_type = ? AND target_id = ?", model.SubscriptionService, *serviceID)
+	}
+	if len(tags) > 0 {
+		targets = targets.Or("target_type = ? AND target_id IN (SELECT id FROM tags WHERE name IN ? AND deleted_at IS NULL)", model.SubscriptionTag, tags)
+	}
+
+	var userIDs []uint
+	err := r.db.Model(&model.Subscription{}).Where(targets).Distinct().Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
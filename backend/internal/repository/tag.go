@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"encoding/json"
+	"techdocs/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type TagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// GetByID retrieves a tag by its ID
+func (r *TagRepository) GetByID(id uint) (*model.Tag, error) {
+	var tag model.Tag
+	err := r.db.First(&tag, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetByName retrieves a tag by its name
+func (r *TagRepository) GetByName(name string) (*model.Tag, error) {
+	var tag model.Tag
+	err := r.db.Where("name = ?", name).First(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// Taken reports whether a tag other than exceptID uses a name
+func (r *TagRepository) Taken(name string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Tag{}).Where("name = ? AND id <> ?", name, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// tagSorts are the fields a tag list may be sorted by
+var tagSorts = []string{"id", "name", "created_at"}
+
+// List retrieves one page of tags, by name unless sorted otherwise. With a
+// prefix only the tags whose name starts with it are listed.
+func (r *TagRepository) List(prefix string, q ListQuery) (*Page[model.Tag], error) {
+	db := r.db
+	if prefix != "" {
+		db = db.Where("tags.name LIKE ?", likePrefix(prefix))
+	}
+	return paginate[model.Tag](db, "tags", q, tagSorts, "name")
+}
+
+// CountDocuments returns how many documents the viewer may see carry each
+// of the given tags
+func (r *TagRepository) CountDocuments(ids []uint, viewer Viewer) (map[uint]int64, error) {
+	db := r.db.Model(&model.Document{}).Scopes(visibleTo(viewer)).
+		Joins("JOIN document_tags dt ON dt.document_id = documents.id")
+	return countBy(db, "dt.tag_id", ids)
+}
+
+// Rename gives a tag a new name, also in the tags recorded by document
+// versions
+func (r *TagRepository) Rename(tag *model.Tag, name string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := retagVersions(tx, tag.Name, name); err != nil {
+			return err
+		}
+		if err := tx.Model(tag).UpdateColumn("name", name).Error; err != nil {
+			return err
+		}
+		tag.Name = name
+		return nil
+	})
+}
+
+// Merge folds a tag into another: documents and subscriptions move over to
+// the target, document versions record the target's name and the merged
+// tag is deleted for good
+func (r *TagRepository) Merge(source, target *model.Tag) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("INSERT IGNORE INTO document_tags (document_id, tag_id) "+
+			"SELECT document_id, ? FROM document_tags WHERE tag_id = ?", target.ID, source.ID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM document_tags WHERE tag_id = ?", source.ID).Error; err != nil {
+			return err
+		}
+
+		err = tx.Exec("UPDATE IGNORE subscriptions SET target_id = ? WHERE target_type = ? AND target_id = ?",
+			target.ID, model.SubscriptionTag, source.ID).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Unscoped().Where("target_type = ? AND target_id = ?", model.SubscriptionTag, source.ID).
+			Delete(&model.Subscription{}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := retagVersions(tx, source.Name, target.Name); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(source).Error
+	})
+}
+
+// retagVersions replaces a tag name in the tags of document versions,
+// dropping it where the new name is already recorded
+func retagVersions(tx *gorm.DB, from, to string) error {
+	needle, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+
+	var versions []model.DocumentVersion
+	err = tx.Select("id", "tags").Where("tags LIKE ?", "%"+likeEscape(string(needle))+"%").Find(&versions).Error
+	if err != nil {
+		return err
+	}
+
+	for _, v := range versions {
+		tags := make(model.StringList, 0, len(v.Tags))
+		changed := false
+		for _, name := range v.Tags {
+			if name == from {
+				name, changed = to, true
+			}
+			if !contains(tags, name) {
+				tags = append(tags, name)
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := tx.Model(&v).UpdateColumn("tags", tags).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
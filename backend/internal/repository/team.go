@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"techdocs/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type TeamRepository struct {
+	db *gorm.DB
+}
+
+func NewTeamRepository(db *gorm.DB) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+// Create creates a new team with its creator as the first team admin
+func (r *TeamRepository) Create(team *model.Team, creatorID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Members").Create(team).Error; err != nil {
+			return err
+		}
+		member := model.TeamMember{TeamID: team.ID, UserID: creatorID, Role: model.TeamRoleAdmin}
+		return tx.Omit("User").Create(&member).Error
+	})
+}
+
+// Update updates an existing team's details
+func (r *TeamRepository) Update(team *model.Team) error {
+	return r.db.Model(team).Select("Name", "Description").Updates(team).Error
+}
+
+// Delete deletes a team for good with its memberships, so its name can be
+// used again. Documents, services and spaces the team owned, deleted ones
+// included, are released.
+func (r *TeamRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, owned := range []any{&model.Document{}, &model.Service{}, &model.Space{}} {
+			err := tx.Unscoped().Model(owned).Where("team_id = ?", id).UpdateColumn("team_id", nil).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("team_id = ?", id).Delete(&model.DocumentPermission{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("team_id = ?", id).Delete(&model.TeamMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&model.Team{}, id).Error
+	})
+}
+
+// Taken reports whether a team other than exceptID already uses a name
+func (r *TeamRepository) Taken(name string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Team{}).Where("name = ? AND id <> ?", name, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// GetByID retrieves a team with its members
+func (r *TeamRepository) GetByID(id uint) (*model.Team, error) {
+	var team model.Team
+	err := r.db.Preload("Members.User").First(&team, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// teamSorts are the fields a team list may be sorted by
+var teamSorts = []string{"id", "name", "created_at"}
+
+// List retrieves one page of teams
+func (r *TeamRepository) List(q ListQuery) (*Page[model.Team], error) {
+	db := r.db.Scopes(createdBetween("teams", q.Filter), updatedBetween("teams", q.Filter))
+	return paginate[model.Team](db, "teams", q, teamSorts, "name")
+}
+
+// GetMemberRole returns a user's role in a team, or an empty string when
+// the user isn't a member
+func (r *TeamRepository) GetMemberRole(teamID, userID uint) (string, error) {
+	var member model.TeamMember
+	err := r.db.Where("team_id = ? AND user_id = ?", teamID, userID).Limit(1).Find(&member).Error
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// GetMembers retrieves the members of a team
+func (r *TeamRepository) GetMembers(teamID uint) ([]model.TeamMember, error) {
+	var members []model.TeamMember
+	err := r.db.Where("team_id = ?", teamID).Preload("User").Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// SetMember adds a user to a team or changes their role
+func (r *TeamRepository) SetMember(member *model.TeamMember) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.TeamMember
+		err := tx.Where("team_id = ? AND user_id = ?", member.TeamID, member.UserID).Limit(1).Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			member.ID = existing.ID
+			member.CreatedAt = existing.CreatedAt
+		}
+		return tx.Omit("User").Save(member).Error
+	})
+}
+
+// RemoveMember removes a user from a team
+func (r *TeamRepository) RemoveMember(teamID, userID uint) error {
+	return r.db.Unscoped().
+		Where("team_id = ? AND user_id = ?", teamID, userID).
+		Delete(&model.TeamMember{}).Error
+}
+
+// CountAdmins counts the admins of a team
+func (r *TeamRepository) CountAdmins(teamID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.TeamMember{}).
+		Where("team_id = ? AND role = ?", teamID, model.TeamRoleAdmin).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"techdocs/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TrashRepository works on soft-deleted documents and services: listing
+// them, bringing them back and removing them for good
+type TrashRepository struct {
+	db *gorm.DB
+}
+
+func NewTrashRepository(db *gorm.DB) *TrashRepository {
+	return &TrashRepository{db: db}
+}
+
+// deletedDocumentSorts and deletedServiceSorts are the fields a trash list
+// may be sorted by
+var (
+	deletedDocumentSorts = append([]string{"deleted_at"}, documentSorts...)
+	deletedServiceSorts  = append([]string{"deleted_at"}, serviceSorts...)
+)
+
+// ListDocuments retrieves one page of the deleted documents whose drafts
+// the viewer may see, most recently deleted first
+func (r *TrashRepository) ListDocuments(viewer Viewer, q ListQuery) (*Page[model.Document], error) {
+	db := r.db.Unscoped().Where("documents.deleted_at IS NOT NULL").
+		Scopes(openTo(viewer), draftAccess(viewer), documentFilters(q.Filter))
+	return paginate[model.Document](db, "documents", q, deletedDocumentSorts, "-deleted_at", "Author", "Tags", "Service", "Team", "Category")
+}
+
+// ListServices retrieves one page of the deleted services, most recently
+// deleted first
+func (r *TrashRepository) ListServices(q ListQuery) (*Page[model.Service], error) {
+	f := q.Filter
+	db := r.db.Unscoped().Where("services.deleted_at IS NOT NULL").
+		Scopes(ownedByTeam(f.TeamID), createdBetween("services", f), updatedBetween("services", f))
+	return paginate[model.Service](db, "services", q, deletedServiceSorts, "-deleted_at", "Team", "Category")
+}
+
+// GetDocument retrieves a deleted document if the viewer may see its draft
+func (r *TrashRepository) GetDocument(id uint, viewer Viewer) (*model.Document, error) {
+	var document model.Document
+	err := r.db.Unscoped().Where("documents.deleted_at IS NOT NULL").
+		Scopes(openTo(viewer), draftAccess(viewer)).
+		Preload("Author").Preload("Tags").Preload("Service").Preload("Team").Preload("Category").
+		First(&document, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// GetService retrieves a deleted service
+func (r *TrashRepository) GetService(id uint) (*model.Service, error) {
+	var service model.Service
+	err := r.db.Unscoped().Where("services.deleted_at IS NOT NULL").Preload("Team").Preload("Category").First(&service, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &service, nil
+}
+
+// RestoreDocument undoes the deletion of a document
+func (r *TrashRepository) RestoreDocument(id uint) error {
+	return r.restore(&model.Document{}, id)
+}
+
+// RestoreService undoes the deletion of a service
+func (r *TrashRepository) RestoreService(id uint) error {
+	return r.restore(&model.Service{}, id)
+}
+
+func (r *TrashRepository) restore(value interface{}, id uint) error {
+	result := r.db.Unscoped().Model(value).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeDocument removes a deleted document for good, along with its tags,
+// comments, versions, grants, reviewers, subscriptions and notifications.
+// Its subpages move to the top of their space.
+func (r *TrashRepository) PurgeDocument(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped().Session(&gorm.Session{})
+		if err := tx.Model(&model.Document{}).Where("parent_id = ?", id).UpdateColumn("parent_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM document_tags WHERE document_id = ?", id).Error; err != nil {
+			return err
+		}
+		// Replies go first, they point at the comment starting their thread
+		if err := tx.Where("document_id = ? AND parent_id IS NOT NULL", id).Delete(&model.Comment{}).Error; err != nil {
+			return err
+		}
+		dependents := []interface{}{
+			&model.Comment{},
+			&model.DocumentVersion{},
+			&model.DocumentPermission{},
+			&model.DocumentReviewer{},
+			&model.Notification{},
+		}
+		for _, dependent := range dependents {
+			if err := tx.Where("document_id = ?", id).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+		err := tx.Where("target_type = ? AND target_id = ?", model.SubscriptionDocument, id).Delete(&model.Subscription{}).Error
+		if err != nil {
+			return err
+		}
+		return purge(tx, &model.Document{}, id)
+	})
+}
+
+// PurgeService removes a deleted service for good. Documents linked to it,
+// and their versions, are unlinked and subscriptions to it are removed.
+func (r *TrashRepository) PurgeService(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped().Session(&gorm.Session{})
+		for _, linked := range []interface{}{&model.Document{}, &model.DocumentVersion{}} {
+			if err := tx.Model(linked).Where("service_id = ?", id).UpdateColumn("service_id", nil).Error; err != nil {
+				return err
+			}
+		}
+		err := tx.Where("target_type = ? AND target_id = ?", model.SubscriptionService, id).Delete(&model.Subscription{}).Error
+		if err != nil {
+			return err
+		}
+		return purge(tx, &model.Service{}, id)
+	})
+}
+
+// purge deletes a soft-deleted row for good. ErrRecordNotFound is returned
+// when there is no such row or it isn't deleted.
+func purge(tx *gorm.DB, value interface{}, id uint) error {
+	result := tx.Where("id = ? AND deleted_at IS NOT NULL", id).Delete(value)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ExpiredDocuments returns the IDs of the documents deleted before a time
+func (r *TrashRepository) ExpiredDocuments(before time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Unscoped().Model(&model.Document{}).Where("deleted_at < ?", before).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ExpiredServices returns the IDs of the services deleted before a time
+func (r *TrashRepository) ExpiredServices(before time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Unscoped().Model(&model.Service{}).Where("deleted_at < ?", before).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
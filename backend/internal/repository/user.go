@@ -62,24 +62,26 @@ func (r *UserRepository) Update(user *model.User) error {
 	return r.db.Save(user).Error
 }
 
+func (r *UserRepository) UpdateRole(id uint, role string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+func (r *UserRepository) UpdateDigest(id uint, digest string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("digest", digest).Error
+}
+
 func (r *UserRepository) Delete(id uint) error {
 	return r.db.Delete(&model.User{}, id).Error
 }
 
-func (r *UserRepository) List(page, limit int) ([]model.User, int64, error) {
-	var users []model.User
-	var total int64
-
-	err := r.db.Model(&model.User{}).Count(&total).Error
-	if err != nil {
-		return nil, 0, err
-	}
+// userSorts are the fields a user list may be sorted by
+var userSorts = []string{"id", "username", "email", "created_at"}
 
-	offset := (page - 1) * limit
-	err = r.db.Offset(offset).Limit(limit).Find(&users).Error
-	if err != nil {
-		return nil, 0, err
+// List retrieves one page of users, optionally only those with a role
+func (r *UserRepository) List(q ListQuery) (*Page[model.User], error) {
+	db := r.db.Scopes(createdBetween("users", q.Filter), updatedBetween("users", q.Filter))
+	if q.Filter.Role != "" {
+		db = db.Where("role = ?", q.Filter.Role)
 	}
-
-	return users, total, nil
-} 
\ No newline at end of file
+	return paginate[model.User](db, "users", q, userSorts, "id")
+}
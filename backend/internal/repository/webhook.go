@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"techdocs/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// Create registers a new webhook
+func (r *WebhookRepository) Create(webhook *model.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+// Update saves a webhook's URL, events, secret and active flag
+func (r *WebhookRepository) Update(webhook *model.Webhook) error {
+	return r.db.Model(webhook).Select("URL", "Events", "Secret", "Active").Updates(webhook).Error
+}
+
+// Delete deletes a webhook and fails its pending deliveries, keeping them
+// in the delivery log
+func (r *WebhookRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.WebhookDelivery{}).
+			Where("webhook_id = ? AND status = ?", id, model.DeliveryPending).
+			Updates(map[string]interface{}{"status": model.DeliveryFailed, "error": "webhook deleted"}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&model.Webhook{}, id).Error
+	})
+}
+
+// GetByID retrieves a webhook by its ID
+func (r *WebhookRepository) GetByID(id uint) (*model.Webhook, error) {
+	var webhook model.Webhook
+	err := r.db.First(&webhook, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// webhookSorts are the fields a webhook list may be sorted by
+var webhookSorts = []string{"id", "url", "created_at"}
+
+// List retrieves one page of webhooks
+func (r *WebhookRepository) List(q ListQuery) (*Page[model.Webhook], error) {
+	db := r.db.Scopes(createdBetween("webhooks", q.Filter))
+	return paginate[model.Webhook](db, "webhooks", q, webhookSorts, "id")
+}
+
+// GetActive retrieves all active webhooks
+func (r *WebhookRepository) GetActive() ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	err := r.db.Where("active = ?", true).Find(&webhooks).Error
+	return webhooks, err
+}
+
+// Enqueue stores new deliveries
+func (r *WebhookRepository) Enqueue(deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Omit(clause.Associations).Create(&deliveries).Error
+}
+
+// ClaimDue picks up to limit pending deliveries that are due, with their
+// webhooks, and postpones them by lease so that concurrent workers skip
+// them while they are being sent
+func (r *WebhookRepository) ClaimDue(limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var due []model.WebhookDelivery
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, time.Now()).
+			Order("next_attempt_at").Limit(limit).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(due))
+		for i, d := range due {
+			ids[i] = d.ID
+		}
+		err = tx.Model(&model.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(lease)).Error
+		if err != nil {
+			return err
+		}
+		// Deliveries of deleted webhooks are left with a zero Webhook
+		return tx.Preload("Webhook").Find(&deliveries, ids).Error
+	})
+	return deliveries, err
+}
+
+// SaveAttempt records the outcome of a delivery attempt
+func (r *WebhookRepository) SaveAttempt(delivery *model.WebhookDelivery) error {
+	return r.db.Model(delivery).
+		Select("Status", "Attempts", "NextAttemptAt", "ResponseStatus", "Error", "DeliveredAt").
+		Updates(delivery).Error
+}
+
+// GetDelivery retrieves one of a webhook's deliveries
+func (r *WebhookRepository) GetDelivery(webhookID, id uint) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := r.db.Where("webhook_id = ?", webhookID).First(&delivery, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// deliverySorts are the fields a delivery log may be sorted by
+var deliverySorts = []string{"id", "created_at", "next_attempt_at"}
+
+// ListDeliveries retrieves one page of a webhook's delivery log, newest
+// first. Filter.Status and Filter.Type narrow it to a state and an event.
+func (r *WebhookRepository) ListDeliveries(webhookID uint, q ListQuery) (*Page[model.WebhookDelivery], error) {
+	f := q.Filter
+	db := r.db.Scopes(createdBetween("webhook_deliveries", f)).Where("webhook_deliveries.webhook_id = ?", webhookID)
+	if f.Status != "" {
+		db = db.Where("webhook_deliveries.status = ?", f.Status)
+	}
+	if f.Type != "" {
+		db = db.Where("webhook_deliveries.event = ?", f.Type)
+	}
+	return paginate[model.WebhookDelivery](db, "webhook_deliveries", q, deliverySorts, "-id")
+}
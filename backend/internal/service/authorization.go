@@ -0,0 +1,110 @@
+package service
+
+import (
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+	"techdocs/pkg/logger"
+)
+
+// ErrForbidden is returned when the acting user may not perform an operation
+var ErrForbidden = Forbidden("forbidden", "you are not allowed to perform this operation")
+
+// ErrInvalidPermissionLevel is returned for an unknown permission level
+var ErrInvalidPermissionLevel = Invalid("invalid_permission_level", "permission level must be viewer, editor or owner")
+
+// Actor is the authenticated user performing an operation, with the
+// permissions granted by their role
+type Actor struct {
+	UserID      uint
+	Role        string
+	Permissions []string
+}
+
+// Can reports whether the actor holds a permission
+func (a Actor) Can(permission string) bool {
+	for _, p := range a.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Viewer returns the actor as a repository viewer for visibility filtering
+func (a Actor) Viewer() repository.Viewer {
+	return repository.Viewer{UserID: a.UserID, Admin: a.Can(model.PermDocumentsAdmin)}
+}
+
+// permissionRank orders permission levels, unknown levels rank lowest
+var permissionRank = map[string]int{
+	model.PermissionViewer: 1,
+	model.PermissionEditor: 2,
+	model.PermissionOwner:  3,
+}
+
+// ValidPermissionLevel reports whether level is a known permission level
+func ValidPermissionLevel(level string) bool {
+	_, ok := permissionRank[level]
+	return ok
+}
+
+// DocumentPolicy decides who may do what with a document. Authors and
+// document admins may do anything. Members of the owning team act as
+// editors and its team admins as owners. Everyone else needs a sufficient
+// grant, held directly or through a team.
+type DocumentPolicy struct {
+	permissionRepo *repository.PermissionRepository
+	teamRepo       *repository.TeamRepository
+}
+
+func NewDocumentPolicy(permissionRepo *repository.PermissionRepository, teamRepo *repository.TeamRepository) *DocumentPolicy {
+	return &DocumentPolicy{
+		permissionRepo: permissionRepo,
+		teamRepo:       teamRepo,
+	}
+}
+
+// CanModify checks whether the actor may update or delete the document,
+// which needs editor access. Denials are logged and reported as ErrForbidden.
+func (p *DocumentPolicy) CanModify(actor Actor, doc *model.Document, action string) error {
+	return p.require(actor, doc, model.PermissionEditor, action)
+}
+
+// CanManagePermissions checks whether the actor may grant or revoke access,
+// which needs owner access
+func (p *DocumentPolicy) CanManagePermissions(actor Actor, doc *model.Document) error {
+	return p.require(actor, doc, model.PermissionOwner, "manage permissions of")
+}
+
+func (p *DocumentPolicy) require(actor Actor, doc *model.Document, level, action string) error {
+	if actor.Can(model.PermDocumentsAdmin) || doc.AuthorID == actor.UserID {
+		return nil
+	}
+
+	granted, err := p.permissionRepo.GetLevels(doc.ID, actor.UserID)
+	if err != nil {
+		return err
+	}
+
+	if doc.TeamID != nil {
+		role, err := p.teamRepo.GetMemberRole(*doc.TeamID, actor.UserID)
+		if err != nil {
+			return err
+		}
+		switch role {
+		case model.TeamRoleAdmin:
+			granted = append(granted, model.PermissionOwner)
+		case model.TeamRoleMember:
+			granted = append(granted, model.PermissionEditor)
+		}
+	}
+
+	for _, g := range granted {
+		if permissionRank[g] >= permissionRank[level] {
+			return nil
+		}
+	}
+
+	logger.Error("Authorization denied: user ID %d may not %s document ID %d", actor.UserID, action, doc.ID)
+	return ErrForbidden
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+	"techdocs/pkg/slug"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidCategory is returned when a document or service is put in
+	// an unknown category
+	ErrInvalidCategory = Invalid("invalid_category", "category not found")
+	// ErrInvalidSlug is returned for a slug other than lowercase letters and
+	// digits separated by dashes
+	ErrInvalidSlug = Invalid("invalid_slug", "slug must be lowercase letters and digits separated by dashes")
+	// ErrInvalidApprovals is returned for a negative number of required
+	// approvals
+	ErrInvalidApprovals = Invalid("invalid_required_approvals", "required approvals must not be negative")
+	// ErrCategoryExists is returned when another category already uses a
+	// name or slug
+	ErrCategoryExists = Duplicate("category_exists", "a category with this name or slug already exists")
+	// ErrCategoryInUse is returned when deleting a category documents or
+	// services are still in, without naming one to move them to
+	ErrCategoryInUse = Conflict("category_in_use", "category is still in use, pick a category to move its documents and services to")
+)
+
+// CategorySummary is a category along with how many documents the viewer
+// may see in it and how many services it holds
+type CategorySummary struct {
+	model.Category
+	DocumentCount int64 `json:"document_count"`
+	ServiceCount  int64 `json:"service_count"`
+}
+
+type CategoryService struct {
+	repo *repository.CategoryRepository
+}
+
+func NewCategoryService(repo *repository.CategoryRepository) *CategoryService {
+	return &CategoryService{
+		repo: repo,
+	}
+}
+
+// CreateCategory creates a new category, last in display order unless a
+// position is given. The slug is made from the name when empty.
+func (s *CategoryService) CreateCategory(category *model.Category) error {
+	if err := s.prepare(category); err != nil {
+		return err
+	}
+	if category.Position == 0 {
+		position, err := s.repo.NextPosition()
+		if err != nil {
+			return err
+		}
+		category.Position = position
+	}
+	return s.repo.Create(category)
+}
+
+// UpdateCategory updates an existing category
+func (s *CategoryService) UpdateCategory(category *model.Category) error {
+	if _, err := s.repo.GetByID(category.ID); err != nil {
+		return notFound(err, "category")
+	}
+	if err := s.prepare(category); err != nil {
+		return err
+	}
+	return s.repo.Update(category)
+}
+
+// DeleteCategory deletes a category. Documents and services still in it
+// move to the category moveTo, which is required then.
+func (s *CategoryService) DeleteCategory(id uint, moveTo *uint) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return notFound(err, "category")
+	}
+
+	if moveTo == nil {
+		inUse, err := s.repo.InUse(id)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return ErrCategoryInUse
+		}
+	} else if *moveTo == id {
+		return ErrInvalidCategory
+	} else if err := requireCategory(s.repo, moveTo); err != nil {
+		return err
+	}
+	return s.repo.Delete(id, moveTo)
+}
+
+// GetCategoryByID retrieves a category by its ID with its counts
+func (s *CategoryService) GetCategoryByID(id uint, actor Actor) (*CategorySummary, error) {
+	category, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err, "category")
+	}
+	summaries, err := s.summarize([]model.Category{*category}, actor)
+	if err != nil {
+		return nil, err
+	}
+	return &summaries[0], nil
+}
+
+// GetAllCategories retrieves one page of categories with their counts
+func (s *CategoryService) GetAllCategories(actor Actor, q repository.ListQuery) (*repository.Page[CategorySummary], error) {
+	page, err := s.repo.List(q)
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := s.summarize(page.Items, actor)
+	if err != nil {
+		return nil, err
+	}
+	return &repository.Page[CategorySummary]{
+		Items:      summaries,
+		Total:      page.Total,
+		Page:       page.Page,
+		Limit:      page.Limit,
+		NextCursor: page.NextCursor,
+	}, nil
+}
+
+// summarize adds the document and service counts to categories
+func (s *CategoryService) summarize(categories []model.Category, actor Actor) ([]CategorySummary, error) {
+	ids := make([]uint, len(categories))
+	for i, category := range categories {
+		ids[i] = category.ID
+	}
+	documents, err := s.repo.CountDocuments(ids, actor.Viewer())
+	if err != nil {
+		return nil, err
+	}
+	services, err := s.repo.CountServices(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]CategorySummary, len(categories))
+	for i, category := range categories {
+		summaries[i] = CategorySummary{
+			Category:      category,
+			DocumentCount: documents[category.ID],
+			ServiceCount:  services[category.ID],
+		}
+	}
+	return summaries, nil
+}
+
+// prepare tidies a category's name and slug, making the slug from the
+// name when empty, and checks the slug is valid and both are free
+func (s *CategoryService) prepare(category *model.Category) error {
+	if category.RequiredApprovals < 0 {
+		return ErrInvalidApprovals
+	}
+	category.Name = strings.TrimSpace(category.Name)
+	category.Slug = strings.TrimSpace(category.Slug)
+	if category.Slug == "" {
+		category.Slug = slug.Make(category.Name)
+	}
+	if !slug.Valid(category.Slug) {
+		return ErrInvalidSlug
+	}
+
+	taken, err := s.repo.Taken(category.Name, category.Slug, category.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrCategoryExists
+	}
+	return nil
+}
+
+// requireCategory checks that a document or service is put in a category
+// that exists, if any
+func requireCategory(repo *repository.CategoryRepository, categoryID *uint) error {
+	if categoryID == nil {
+		return nil
+	}
+	_, err := repo.GetByID(*categoryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidCategory
+	}
+	return err
+}
+
+// categoryName returns the name of a document's category, or "" when it
+// has none or it isn't loaded
+func categoryName(doc *model.Document) string {
+	if doc.Category == nil {
+		return ""
+	}
+	return doc.Category.Name
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidParent is returned when a reply points at a comment on
+	// another document
+	ErrInvalidParent = Invalid("invalid_parent_comment", "parent comment does not belong to this document")
+	// ErrInvalidAnchor is returned when an anchor doesn't match the document
+	ErrInvalidAnchor = Invalid("invalid_anchor", "anchor must name a heading or a line range of the document")
+	// ErrNotThread is returned when a reply is anchored or resolved
+	ErrNotThread = Invalid("not_a_thread", "only the first comment of a thread can be anchored or resolved")
+)
+
+type CommentService struct {
+	repo          *repository.CommentRepository
+	documentRepo  *repository.DocumentRepository
+	policy        *DocumentPolicy
+	notifications *NotificationService
+	webhooks      *WebhookService
+}
+
+func NewCommentService(
+	repo *repository.CommentRepository,
+	documentRepo *repository.DocumentRepository,
+	permissionRepo *repository.PermissionRepository,
+	teamRepo *repository.TeamRepository,
+	notifications *NotificationService,
+	webhooks *WebhookService,
+) *CommentService {
+	return &CommentService{
+		repo:          repo,
+		documentRepo:  documentRepo,
+		policy:        NewDocumentPolicy(permissionRepo, teamRepo),
+		notifications: notifications,
+		webhooks:      webhooks,
+	}
+}
+
+// CreateComment adds a comment to a document the actor may see. A reply to
+// a reply joins the thread of the comment it answers.
+func (s *CommentService) CreateComment(documentID uint, comment *model.Comment, actor Actor) error {
+	doc, err := s.documentRepo.GetByID(documentID, actor.Viewer())
+	if err != nil {
+		return notFound(err, "document")
+	}
+
+	if comment.ParentID != nil {
+		parent, err := s.repo.GetByID(*comment.ParentID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidParent
+		}
+		if err != nil {
+			return err
+		}
+		if parent.DocumentID != documentID {
+			return ErrInvalidParent
+		}
+		if parent.ParentID != nil {
+			comment.ParentID = parent.ParentID
+		}
+		if hasAnchor(comment) {
+			return ErrNotThread
+		}
+	} else if err := validateAnchor(doc.Content, comment); err != nil {
+		return err
+	}
+
+	comment.DocumentID = documentID
+	comment.UserID = actor.UserID
+	comment.Resolved = false
+	comment.ResolvedBy = nil
+	comment.ResolvedAt = nil
+	if err := s.repo.Create(comment); err != nil {
+		return err
+	}
+
+	s.notifications.NotifyCommentMentions(doc, comment, "", actor)
+	commentID := comment.ID
+	s.notifications.NotifySubscribers(doc, model.NotificationComment, &commentID, actor)
+	s.webhooks.DispatchComment(doc, comment, actor)
+	return s.reload(comment)
+}
+
+// UpdateComment changes the content and anchor of a comment. Only its
+// author may edit it.
+func (s *CommentService) UpdateComment(documentID uint, update *model.Comment, actor Actor) error {
+	doc, existing, err := s.getComment(documentID, update.ID, actor)
+	if err != nil {
+		return err
+	}
+	if existing.UserID != actor.UserID {
+		return ErrForbidden
+	}
+
+	if existing.ParentID != nil {
+		if hasAnchor(update) {
+			return ErrNotThread
+		}
+	} else if err := validateAnchor(doc.Content, update); err != nil {
+		return err
+	}
+
+	previous := existing.Content
+	existing.Content = update.Content
+	existing.AnchorHeading = update.AnchorHeading
+	existing.AnchorStartLine = update.AnchorStartLine
+	existing.AnchorEndLine = update.AnchorEndLine
+	if err := s.repo.Update(existing); err != nil {
+		return err
+	}
+
+	s.notifications.NotifyCommentMentions(doc, existing, previous, actor)
+	*update = *existing
+	return nil
+}
+
+// DeleteComment deletes a comment and its replies. Besides its author,
+// anyone who may edit the document may delete it.
+func (s *CommentService) DeleteComment(documentID, commentID uint, actor Actor) error {
+	doc, comment, err := s.getComment(documentID, commentID, actor)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != actor.UserID {
+		if err := s.policy.CanModify(actor, doc, "delete comments on"); err != nil {
+			return err
+		}
+	}
+	return s.repo.Delete(commentID)
+}
+
+// SetThreadResolved resolves or reopens a thread. The thread's author and
+// anyone who may edit the document may do so.
+func (s *CommentService) SetThreadResolved(documentID, commentID uint, resolved bool, actor Actor) (*model.Comment, error) {
+	doc, comment, err := s.getComment(documentID, commentID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if comment.ParentID != nil {
+		return nil, ErrNotThread
+	}
+	if comment.UserID != actor.UserID {
+		if err := s.policy.CanModify(actor, doc, "resolve comments on"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.SetResolved(commentID, resolved, actor.UserID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(commentID)
+}
+
+// GetComment retrieves a comment with its replies
+func (s *CommentService) GetComment(documentID, commentID uint, actor Actor) (*model.Comment, error) {
+	_, comment, err := s.getComment(documentID, commentID, actor)
+	return comment, err
+}
+
+// GetThreads retrieves one page of a document's comment threads
+func (s *CommentService) GetThreads(documentID uint, actor Actor, q repository.ListQuery) (*repository.Page[model.Comment], error) {
+	if _, err := s.documentRepo.GetByID(documentID, actor.Viewer()); err != nil {
+		return nil, notFound(err, "document")
+	}
+	return s.repo.ListThreads(documentID, q)
+}
+
+// getComment loads a document the actor may see and one of its comments.
+// A comment on another document is reported as not found.
+func (s *CommentService) getComment(documentID, commentID uint, actor Actor) (*model.Document, *model.Comment, error) {
+	doc, err := s.documentRepo.GetByID(documentID, actor.Viewer())
+	if err != nil {
+		return nil, nil, notFound(err, "document")
+	}
+	comment, err := s.repo.GetByID(commentID)
+	if err != nil {
+		return nil, nil, notFound(err, "comment")
+	}
+	if comment.DocumentID != documentID {
+		return nil, nil, notFound(gorm.ErrRecordNotFound, "comment")
+	}
+	return doc, comment, nil
+}
+
+// reload replaces a freshly written comment with the stored one, so the
+// response carries its author
+func (s *CommentService) reload(comment *model.Comment) error {
+	stored, err := s.repo.GetByID(comment.ID)
+	if err != nil {
+		return notFound(err, "comment")
+	}
+	*comment = *stored
+	return nil
+}
+
+func hasAnchor(comment *model.Comment) bool {
+	return comment.AnchorHeading != "" || comment.AnchorStartLine != nil || comment.AnchorEndLine != nil
+}
+
+// validateAnchor checks that a comment is anchored to either a heading of
+// the content or a range of its lines, if to anything at all. A range
+// without an end covers a single line.
+func validateAnchor(content string, comment *model.Comment) error {
+	if comment.AnchorHeading != "" {
+		if comment.AnchorStartLine != nil || comment.AnchorEndLine != nil {
+			return ErrInvalidAnchor
+		}
+		if !hasHeading(content, comment.AnchorHeading) {
+			return ErrInvalidAnchor
+		}
+		return nil
+	}
+
+	if comment.AnchorStartLine == nil {
+		if comment.AnchorEndLine != nil {
+			return ErrInvalidAnchor
+		}
+		return nil
+	}
+	if comment.AnchorEndLine == nil {
+		end := *comment.AnchorStartLine
+		comment.AnchorEndLine = &end
+	}
+
+	start, end := *comment.AnchorStartLine, *comment.AnchorEndLine
+	lines := strings.Count(content, "\n") + 1
+	if start < 1 || end < start || end > lines {
+		return ErrInvalidAnchor
+	}
+	return nil
+}
+
+// hasHeading reports whether content has a Markdown heading with the given
+// text, ignoring case and surrounding whitespace
+func hasHeading(content, heading string) bool {
+	heading = strings.TrimSpace(heading)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		text := strings.TrimLeft(line, "#")
+		level := len(line) - len(text)
+		if level < 1 || level > 6 || (text != "" && text[0] != ' ' && text[0] != '\t') {
+			continue
+		}
+		text = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(text), "#"))
+		if strings.EqualFold(text, heading) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+	"techdocs/pkg/logger"
+	"techdocs/pkg/notify"
+	texttemplate "text/template"
+	"time"
+)
+
+// E-mail delivery settings. Notifications older than the windows are
+// never e-mailed, so a backlog doesn't flood inboxes after an outage.
+const (
+	deliveryInterval = time.Minute
+	immediateWindow  = 24 * time.Hour
+	digestWindow     = 48 * time.Hour
+	maxEmailAttempts = 5
+	sendTimeout      = 30 * time.Second
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt.tmpl"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html.tmpl"))
+)
+
+// emailItem is one notification as shown in an e-mail
+type emailItem struct {
+	Notification model.Notification
+	Link         string
+}
+
+// emailData is what the e-mail templates render. Notification and Link are
+// set for single notifications, Items for digests.
+type emailData struct {
+	User   model.User
+	AppURL string
+	emailItem
+	Items []emailItem
+}
+
+// emailQueue is the part of the notification repository e-mail delivery
+// works from
+type emailQueue interface {
+	GetUnemailed(digest string, since time.Time, maxAttempts int) ([]model.Notification, error)
+	MarkEmailed(ids []uint) error
+	RecordEmailFailure(ids []uint) error
+}
+
+// DeliveryService e-mails notifications, one by one to users who want them
+// as they happen and as a daily digest to the others
+type DeliveryService struct {
+	repo       emailQueue
+	notifier   notify.Notifier
+	appURL     string
+	digestHour int
+}
+
+func NewDeliveryService(repo *repository.NotificationRepository, notifier notify.Notifier, appURL string, digestHour int) *DeliveryService {
+	return &DeliveryService{
+		repo:       repo,
+		notifier:   notifier,
+		appURL:     strings.TrimRight(appURL, "/"),
+		digestHour: digestHour,
+	}
+}
+
+// Run delivers e-mails until ctx is cancelled: pending notifications every
+// minute and digests once a day at the digest hour
+func (s *DeliveryService) Run(ctx context.Context) {
+	ticker := time.NewTicker(deliveryInterval)
+	defer ticker.Stop()
+	digest := time.NewTimer(time.Until(nextDigest(time.Now(), s.digestHour)))
+	defer digest.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.SendImmediate(ctx)
+		case <-digest.C:
+			s.SendDigests(ctx)
+			digest.Reset(time.Until(nextDigest(time.Now(), s.digestHour)))
+		}
+	}
+}
+
+// SendImmediate e-mails every pending notification of users who want them
+// as they happen
+func (s *DeliveryService) SendImmediate(ctx context.Context) {
+	notifications, err := s.repo.GetUnemailed(model.DigestImmediate, time.Now().Add(-immediateWindow), maxEmailAttempts)
+	if err != nil {
+		logger.Error("Failed to load notifications to e-mail: %v", err)
+		return
+	}
+
+	for _, n := range notifications {
+		data := emailData{User: n.User, AppURL: s.appURL, emailItem: s.item(n)}
+		s.send(ctx, n.User, n.Message, "notification", data, []uint{n.ID})
+	}
+}
+
+// SendDigests e-mails each user who prefers a daily digest one message
+// listing all their pending notifications
+func (s *DeliveryService) SendDigests(ctx context.Context) {
+	notifications, err := s.repo.GetUnemailed(model.DigestDaily, time.Now().Add(-digestWindow), maxEmailAttempts)
+	if err != nil {
+		logger.Error("Failed to load notifications for digests: %v", err)
+		return
+	}
+
+	// Notifications come ordered by user
+	for start := 0; start < len(notifications); {
+		end := start
+		var items []emailItem
+		var ids []uint
+		for ; end < len(notifications) && notifications[end].UserID == notifications[start].UserID; end++ {
+			items = append(items, s.item(notifications[end]))
+			ids = append(ids, notifications[end].ID)
+		}
+
+		user := notifications[start].User
+		subject := fmt.Sprintf("Your daily digest: %d notifications", len(items))
+		if len(items) == 1 {
+			subject = "Your daily digest: 1 notification"
+		}
+		s.send(ctx, user, subject, "digest", emailData{User: user, AppURL: s.appURL, Items: items}, ids)
+		start = end
+	}
+}
+
+// send renders and e-mails a message, then records the outcome on the
+// notifications it covers
+func (s *DeliveryService) send(ctx context.Context, user model.User, subject, template string, data emailData, ids []uint) {
+	var text, html bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&text, template+".txt.tmpl", data); err != nil {
+		logger.Error("Failed to render %s e-mail for user ID %d: %v", template, user.ID, err)
+		return
+	}
+	if err := htmlTemplates.ExecuteTemplate(&html, template+".html.tmpl", data); err != nil {
+		logger.Error("Failed to render %s e-mail for user ID %d: %v", template, user.ID, err)
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	err := s.notifier.Notify(sendCtx, notify.Message{
+		To:      []string{user.Email},
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+	})
+	if err != nil {
+		logger.Error("Failed to e-mail user ID %d: %v", user.ID, err)
+		if err := s.repo.RecordEmailFailure(ids); err != nil {
+			logger.Error("Failed to record e-mail failure for user ID %d: %v", user.ID, err)
+		}
+		return
+	}
+
+	if err := s.repo.MarkEmailed(ids); err != nil {
+		logger.Error("Failed to mark notifications e-mailed for user ID %d: %v", user.ID, err)
+		return
+	}
+	logger.Info("E-mailed %d notification(s) to user ID %d", len(ids), user.ID)
+}
+
+// item links a notification to its document, unless the document is gone
+func (s *DeliveryService) item(n model.Notification) emailItem {
+	item := emailItem{Notification: n}
+	if n.DocumentID != nil && n.Type != model.NotificationDocumentDeleted {
+		item.Link = fmt.Sprintf("%s/document/%d", s.appURL, *n.DocumentID)
+	}
+	return item
+}
+
+// nextDigest returns the next time the clock strikes hour after now
+func nextDigest(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
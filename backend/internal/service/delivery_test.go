@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"techdocs/internal/model"
+	"techdocs/pkg/logger"
+	"techdocs/pkg/notify"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestNextDigest(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		hour int
+		want time.Time
+	}{
+		{"before the hour", date(2024, 3, 5, 6, 30, 0), 8, date(2024, 3, 5, 8, 0, 0)},
+		{"on the hour", date(2024, 3, 5, 8, 0, 0), 8, date(2024, 3, 6, 8, 0, 0)},
+		{"after the hour", date(2024, 3, 5, 8, 0, 1), 8, date(2024, 3, 6, 8, 0, 0)},
+		{"end of month", date(2024, 1, 31, 23, 0, 0), 8, date(2024, 2, 1, 8, 0, 0)},
+		{"end of year", date(2024, 12, 31, 9, 0, 0), 8, date(2025, 1, 1, 8, 0, 0)},
+		{"midnight", date(2024, 3, 5, 0, 0, 1), 0, date(2024, 3, 6, 0, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextDigest(tt.now, tt.hour); !got.Equal(tt.want) {
+				t.Errorf("nextDigest(%v, %d) = %v, want %v", tt.now, tt.hour, got, tt.want)
+			}
+		})
+	}
+}
+
+func date(year int, month time.Month, day, hour, min, sec int) time.Time {
+	return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+}
+
+// fakeEmailQueue hands out fixed notifications and records what became
+// of them
+type fakeEmailQueue struct {
+	notifications []model.Notification
+	digest        string
+	emailed       [][]uint
+	failed        [][]uint
+}
+
+func (q *fakeEmailQueue) GetUnemailed(digest string, since time.Time, maxAttempts int) ([]model.Notification, error) {
+	q.digest = digest
+	return q.notifications, nil
+}
+
+func (q *fakeEmailQueue) MarkEmailed(ids []uint) error {
+	q.emailed = append(q.emailed, ids)
+	return nil
+}
+
+func (q *fakeEmailQueue) RecordEmailFailure(ids []uint) error {
+	q.failed = append(q.failed, ids)
+	return nil
+}
+
+// recordingNotifier keeps the messages it is given and fails those sent
+// to the addresses in fail
+type recordingNotifier struct {
+	fail map[string]bool
+	sent []notify.Message
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, msg notify.Message) error {
+	n.sent = append(n.sent, msg)
+	if n.fail[msg.To[0]] {
+		return errors.New("mailbox unavailable")
+	}
+	return nil
+}
+
+func TestSendDigestsGroupsNotificationsByUser(t *testing.T) {
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	ada := model.User{Model: gorm.Model{ID: 1}, Username: "ada", Email: "ada@example.com"}
+	grace := model.User{Model: gorm.Model{ID: 2}, Username: "grace", Email: "grace@example.com"}
+	linus := model.User{Model: gorm.Model{ID: 3}, Username: "linus", Email: "linus@example.com"}
+	notification := func(id uint, user model.User, message string) model.Notification {
+		return model.Notification{Model: gorm.Model{ID: id, CreatedAt: time.Now()}, UserID: user.ID, User: user, Message: message}
+	}
+
+	queue := &fakeEmailQueue{notifications: []model.Notification{
+		notification(1, ada, "Runbook was updated"),
+		notification(2, ada, "grace mentioned you in Deploys"),
+		notification(3, grace, "Runbook was published"),
+		notification(4, linus, "Runbook was archived"),
+	}}
+	notifier := &recordingNotifier{fail: map[string]bool{"linus@example.com": true}}
+	s := &DeliveryService{repo: queue, notifier: notifier, appURL: "https://docs.example.com"}
+
+	s.SendDigests(context.Background())
+
+	if queue.digest != model.DigestDaily {
+		t.Errorf("loaded notifications of %q users, want %q", queue.digest, model.DigestDaily)
+	}
+
+	want := []struct {
+		to       string
+		subject  string
+		messages []string
+	}{
+		{"ada@example.com", "Your daily digest: 2 notifications", []string{"Runbook was updated", "grace mentioned you in Deploys"}},
+		{"grace@example.com", "Your daily digest: 1 notification", []string{"Runbook was published"}},
+		{"linus@example.com", "Your daily digest: 1 notification", []string{"Runbook was archived"}},
+	}
+	if len(notifier.sent) != len(want) {
+		t.Fatalf("sent %d e-mails, want %d", len(notifier.sent), len(want))
+	}
+	for i, w := range want {
+		msg := notifier.sent[i]
+		if len(msg.To) != 1 || msg.To[0] != w.to {
+			t.Errorf("e-mail %d sent to %v, want %s", i, msg.To, w.to)
+		}
+		if msg.Subject != w.subject {
+			t.Errorf("e-mail %d subject = %q, want %q", i, msg.Subject, w.subject)
+		}
+		for _, m := range w.messages {
+			if !strings.Contains(msg.Text, m) || !strings.Contains(msg.HTML, m) {
+				t.Errorf("e-mail %d doesn't list %q", i, m)
+			}
+		}
+	}
+
+	if wantEmailed := [][]uint{{1, 2}, {3}}; !reflect.DeepEqual(queue.emailed, wantEmailed) {
+		t.Errorf("marked e-mailed %v, want %v", queue.emailed, wantEmailed)
+	}
+	if wantFailed := [][]uint{{4}}; !reflect.DeepEqual(queue.failed, wantFailed) {
+		t.Errorf("recorded failures for %v, want %v", queue.failed, wantFailed)
+	}
+}
@@ -1,49 +1,441 @@
 package service
 
 import (
+	"fmt"
+	"sort"
 	"techdocs/internal/model"
 	"techdocs/internal/repository"
+	"techdocs/pkg/diff"
 )
 
 type DocumentService struct {
-	repo *repository.DocumentRepository
+	repo           *repository.DocumentRepository
+	userRepo       *repository.UserRepository
+	permissionRepo *repository.PermissionRepository
+	teamRepo       *repository.TeamRepository
+	reviewRepo     *repository.ReviewRepository
+	roleRepo       *repository.RoleRepository
+	spaceRepo      *repository.SpaceRepository
+	categoryRepo   *repository.CategoryRepository
+	approvals      ApprovalRules
+	policy         *DocumentPolicy
+	notifications  *NotificationService
+	webhooks       *WebhookService
 }
 
-func NewDocumentService(repo *repository.DocumentRepository) *DocumentService {
-	return &DocumentService{repo: repo}
+func NewDocumentService(
+	repo *repository.DocumentRepository,
+	userRepo *repository.UserRepository,
+	permissionRepo *repository.PermissionRepository,
+	teamRepo *repository.TeamRepository,
+	reviewRepo *repository.ReviewRepository,
+	roleRepo *repository.RoleRepository,
+	spaceRepo *repository.SpaceRepository,
+	categoryRepo *repository.CategoryRepository,
+	approvals ApprovalRules,
+	notifications *NotificationService,
+	webhooks *WebhookService,
+) *DocumentService {
+	return &DocumentService{
+		repo:           repo,
+		userRepo:       userRepo,
+		permissionRepo: permissionRepo,
+		teamRepo:       teamRepo,
+		reviewRepo:     reviewRepo,
+		roleRepo:       roleRepo,
+		spaceRepo:      spaceRepo,
+		categoryRepo:   categoryRepo,
+		approvals:      approvals,
+		policy:         NewDocumentPolicy(permissionRepo, teamRepo),
+		notifications:  notifications,
+		webhooks:       webhooks,
+	}
 }
 
-// CreateDocument creates a new document
-func (s *DocumentService) CreateDocument(document *model.Document) error {
-	return s.repo.Create(document)
+// CreateDocument creates a new document as a draft, last among the pages
+// of its space or below its parent. Only members of a team may make it the
+// document's owner.
+func (s *DocumentService) CreateDocument(document *model.Document, actor Actor) error {
+	if err := requireTeamMember(s.teamRepo, actor, document.TeamID); err != nil {
+		return err
+	}
+	if err := requireCategory(s.categoryRepo, document.CategoryID); err != nil {
+		return err
+	}
+	spaceID, err := s.placement(document.SpaceID, document.ParentID, actor)
+	if err != nil {
+		return err
+	}
+	document.SpaceID = spaceID
+	document.Space = nil
+	document.Category = nil
+	document.Position, err = s.repo.NextPosition(document.SpaceID, document.ParentID)
+	if err != nil {
+		return err
+	}
+
+	document.Status = model.StatusDraft
+	document.PublishedVersion = nil
+	if err := s.repo.Create(document); err != nil {
+		return err
+	}
+	if err := s.reload(document); err != nil {
+		return err
+	}
+
+	s.notifications.NotifyDocumentMentions(document, "", actor)
+	s.notifications.NotifySubscribers(document, model.NotificationDocumentCreated, nil, actor)
+	s.webhooks.DispatchDocument(model.EventDocumentCreated, document, actor)
+	return nil
 }
 
-// UpdateDocument updates an existing document
-func (s *DocumentService) UpdateDocument(document *model.Document) error {
-	return s.repo.Update(document)
+// UpdateDocument updates an existing document, keeping the previous state
+// as a new version. The author of a document never changes. Only drafts
+// and published documents may be edited, the latter becoming drafts.
+func (s *DocumentService) UpdateDocument(document *model.Document, actor Actor) error {
+	existing, err := s.repo.GetByID(document.ID, actor.Viewer())
+	if err != nil {
+		return notFound(err, "document")
+	}
+	if err := s.policy.CanModify(actor, existing, "update"); err != nil {
+		return err
+	}
+	if !editable(existing) {
+		return ErrInvalidTransition
+	}
+	if !equalIDs(document.TeamID, existing.TeamID) {
+		if err := requireTeamMember(s.teamRepo, actor, document.TeamID); err != nil {
+			return err
+		}
+	}
+	if err := requireCategory(s.categoryRepo, document.CategoryID); err != nil {
+		return err
+	}
+
+	document.AuthorID = existing.AuthorID
+	document.Status = existing.Status
+	document.PublishedVersion = existing.PublishedVersion
+	if err := s.repo.UpdateWithVersion(document, actor.UserID); err != nil {
+		return err
+	}
+	if err := s.reload(document); err != nil {
+		return err
+	}
+
+	s.notifications.NotifyDocumentMentions(document, existing.Content, actor)
+	s.notifications.NotifySubscribers(document, model.NotificationDocumentUpdated, nil, actor)
+	s.webhooks.DispatchDocument(model.EventDocumentUpdated, document, actor)
+	return nil
+}
+
+// reload replaces a freshly written document with the stored one, so it
+// carries its author, tags and the other documents it references
+func (s *DocumentService) reload(document *model.Document) error {
+	stored, err := s.repo.GetByID(document.ID, repository.Viewer{Admin: true})
+	if err != nil {
+		return err
+	}
+	*document = *stored
+	return nil
 }
 
 // DeleteDocument deletes a document
-func (s *DocumentService) DeleteDocument(id uint) error {
-	return s.repo.Delete(id)
+func (s *DocumentService) DeleteDocument(id uint, actor Actor) error {
+	existing, err := s.repo.GetByID(id, actor.Viewer())
+	if err != nil {
+		return notFound(err, "document")
+	}
+	if err := s.policy.CanModify(actor, existing, "delete"); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.notifications.NotifySubscribers(existing, model.NotificationDocumentDeleted, nil, actor)
+	s.webhooks.DispatchDocument(model.EventDocumentDeleted, existing, actor)
+	return nil
 }
 
 // GetDocumentByID retrieves a document by its ID
-func (s *DocumentService) GetDocumentByID(id uint) (*model.Document, error) {
-	return s.repo.GetByID(id)
+func (s *DocumentService) GetDocumentByID(id uint, actor Actor) (*model.Document, error) {
+	doc, err := s.repo.GetByID(id, actor.Viewer())
+	if err != nil {
+		return nil, notFound(err, "document")
+	}
+	return doc, nil
+}
+
+// GetAllDocuments retrieves one page of the documents the actor may see
+func (s *DocumentService) GetAllDocuments(actor Actor, q repository.ListQuery) (*repository.Page[model.Document], error) {
+	return s.repo.List(actor.Viewer(), q)
+}
+
+// GetDocumentVersions retrieves the version history of a document
+func (s *DocumentService) GetDocumentVersions(documentID uint, actor Actor) ([]model.DocumentVersion, error) {
+	if _, err := s.repo.GetByID(documentID, actor.Viewer()); err != nil {
+		return nil, notFound(err, "document")
+	}
+	return s.repo.GetVersions(documentID)
+}
+
+// GetDocumentVersion retrieves a single version of a document
+func (s *DocumentService) GetDocumentVersion(documentID uint, version int, actor Actor) (*model.DocumentVersion, error) {
+	if _, err := s.repo.GetByID(documentID, actor.Viewer()); err != nil {
+		return nil, notFound(err, "document")
+	}
+	v, err := s.repo.GetVersion(documentID, version)
+	if err != nil {
+		return nil, notFound(err, "document version")
+	}
+	return v, nil
+}
+
+// RestoreDocumentVersion brings back a stored version as the new head of a
+// document, including its tags and service
+func (s *DocumentService) RestoreDocumentVersion(documentID uint, version int, actor Actor) (*model.Document, error) {
+	existing, err := s.repo.GetByID(documentID, actor.Viewer())
+	if err != nil {
+		return nil, notFound(err, "document")
+	}
+	if err := s.policy.CanModify(actor, existing, "restore"); err != nil {
+		return nil, err
+	}
+	if !editable(existing) {
+		return nil, ErrInvalidTransition
+	}
+
+	restored, err := s.repo.RestoreVersion(documentID, version, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifications.NotifySubscribers(restored, model.NotificationDocumentUpdated, nil, actor)
+	s.webhooks.DispatchDocument(model.EventDocumentUpdated, restored, actor)
+	return restored, nil
+}
+
+// GetDocumentPermissions retrieves the grants on a document
+func (s *DocumentService) GetDocumentPermissions(documentID uint, actor Actor) ([]model.DocumentPermission, error) {
+	if _, err := s.repo.GetByID(documentID, actor.Viewer()); err != nil {
+		return nil, notFound(err, "document")
+	}
+	return s.permissionRepo.GetByDocument(documentID)
+}
+
+// GrantDocumentPermission gives a user viewer, editor or owner access to a
+// document, replacing any previous grant
+func (s *DocumentService) GrantDocumentPermission(documentID, userID uint, level string, actor Actor) (*model.DocumentPermission, error) {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return nil, notFound(err, "user")
+	}
+	return s.grant(&model.DocumentPermission{DocumentID: documentID, UserID: &userID, Level: level}, actor)
+}
+
+// GrantTeamDocumentPermission gives every member of a team viewer, editor
+// or owner access to a document, replacing any previous grant
+func (s *DocumentService) GrantTeamDocumentPermission(documentID, teamID uint, level string, actor Actor) (*model.DocumentPermission, error) {
+	if _, err := s.teamRepo.GetByID(teamID); err != nil {
+		return nil, notFound(err, "team")
+	}
+	return s.grant(&model.DocumentPermission{DocumentID: documentID, TeamID: &teamID, Level: level}, actor)
+}
+
+func (s *DocumentService) grant(permission *model.DocumentPermission, actor Actor) (*model.DocumentPermission, error) {
+	if !ValidPermissionLevel(permission.Level) {
+		return nil, ErrInvalidPermissionLevel
+	}
+
+	doc, err := s.repo.GetByID(permission.DocumentID, actor.Viewer())
+	if err != nil {
+		return nil, notFound(err, "document")
+	}
+	if err := s.policy.CanManagePermissions(actor, doc); err != nil {
+		return nil, err
+	}
+
+	permission.GrantedBy = actor.UserID
+	if err := s.permissionRepo.Grant(permission); err != nil {
+		return nil, err
+	}
+	return permission, nil
 }
 
-// GetAllDocuments retrieves all documents
-func (s *DocumentService) GetAllDocuments() ([]model.Document, error) {
-	return s.repo.GetAll()
+// RevokeDocumentPermission removes a user's grant on a document
+func (s *DocumentService) RevokeDocumentPermission(documentID, userID uint, actor Actor) error {
+	return s.revoke(documentID, &userID, nil, actor)
+}
+
+// RevokeTeamDocumentPermission removes a team's grant on a document
+func (s *DocumentService) RevokeTeamDocumentPermission(documentID, teamID uint, actor Actor) error {
+	return s.revoke(documentID, nil, &teamID, actor)
+}
+
+func (s *DocumentService) revoke(documentID uint, userID, teamID *uint, actor Actor) error {
+	doc, err := s.repo.GetByID(documentID, actor.Viewer())
+	if err != nil {
+		return notFound(err, "document")
+	}
+	if err := s.policy.CanManagePermissions(actor, doc); err != nil {
+		return err
+	}
+
+	return s.permissionRepo.Revoke(documentID, userID, teamID)
+}
+
+// DocumentRevision identifies the live document (Version 0) or one of its
+// stored versions when diffing
+type DocumentRevision struct {
+	Version    int
+	Title      string
+	CategoryID *uint
+	Tags       []string
+	ServiceID  *uint
+	Content    string
+}
+
+// Label returns a short name for the revision, e.g. "v3" or "current"
+func (r *DocumentRevision) Label() string {
+	if r.Version == 0 {
+		return "current"
+	}
+	return fmt.Sprintf("v%d", r.Version)
+}
+
+// FieldChange describes a metadata field that differs between two revisions
+type FieldChange struct {
+	Field   string      `json:"field"`
+	From    interface{} `json:"from"`
+	To      interface{} `json:"to"`
+	Added   []string    `json:"added,omitempty"`
+	Removed []string    `json:"removed,omitempty"`
+}
+
+// DocumentDiff is the difference between two revisions of a document
+type DocumentDiff struct {
+	DocumentID uint          `json:"document_id"`
+	From       string        `json:"from"`
+	To         string        `json:"to"`
+	Fields     []FieldChange `json:"fields"`
+	Hunks      []diff.Hunk   `json:"hunks"`
+}
+
+// Unified renders the content changes in unified diff format
+func (d *DocumentDiff) Unified() string {
+	return diff.Unified(
+		fmt.Sprintf("document/%d@%s", d.DocumentID, d.From),
+		fmt.Sprintf("document/%d@%s", d.DocumentID, d.To),
+		d.Hunks,
+	)
+}
+
+// DiffDocument compares two revisions of a document. A version number of 0
+// refers to the live document.
+func (s *DocumentService) DiffDocument(documentID uint, from, to int, actor Actor) (*DocumentDiff, error) {
+	doc, err := s.repo.GetByID(documentID, actor.Viewer())
+	if err != nil {
+		return nil, notFound(err, "document")
+	}
+
+	fromRev, err := s.getRevision(doc, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := s.getRevision(doc, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DocumentDiff{
+		DocumentID: documentID,
+		From:       fromRev.Label(),
+		To:         toRev.Label(),
+		Fields:     diffFields(fromRev, toRev),
+		Hunks:      diff.Lines(fromRev.Content, toRev.Content, diff.DefaultContext),
+	}, nil
+}
+
+func (s *DocumentService) getRevision(doc *model.Document, version int) (*DocumentRevision, error) {
+	if version == 0 {
+		tags := make([]string, 0, len(doc.Tags))
+		for _, tag := range doc.Tags {
+			tags = append(tags, tag.Name)
+		}
+		return &DocumentRevision{
+			Title:      doc.Title,
+			CategoryID: doc.CategoryID,
+			Tags:       tags,
+			ServiceID:  doc.ServiceID,
+			Content:    doc.Content,
+		}, nil
+	}
+
+	v, err := s.repo.GetVersion(doc.ID, version)
+	if err != nil {
+		return nil, notFound(err, "document version")
+	}
+	return &DocumentRevision{
+		Version:    v.Version,
+		Title:      v.Title,
+		CategoryID: v.CategoryID,
+		Tags:       v.Tags,
+		ServiceID:  v.ServiceID,
+		Content:    v.Content,
+	}, nil
+}
+
+func diffFields(from, to *DocumentRevision) []FieldChange {
+	changes := []FieldChange{}
+	if from.Title != to.Title {
+		changes = append(changes, FieldChange{Field: "title", From: from.Title, To: to.Title})
+	}
+	if !equalIDs(from.CategoryID, to.CategoryID) {
+		changes = append(changes, FieldChange{Field: "category_id", From: from.CategoryID, To: to.CategoryID})
+	}
+
+	added, removed := diffSets(from.Tags, to.Tags)
+	if len(added) > 0 || len(removed) > 0 {
+		changes = append(changes, FieldChange{
+			Field:   "tags",
+			From:    from.Tags,
+			To:      to.Tags,
+			Added:   added,
+			Removed: removed,
+		})
+	}
+
+	if !equalIDs(from.ServiceID, to.ServiceID) {
+		changes = append(changes, FieldChange{Field: "service_id", From: from.ServiceID, To: to.ServiceID})
+	}
+	return changes
 }
 
-// GetDocumentsByAuthor retrieves all documents by an author ID
-func (s *DocumentService) GetDocumentsByAuthor(authorID uint) ([]model.Document, error) {
-	return s.repo.GetByAuthor(authorID)
+// diffSets returns the names present only in b and only in a
+func diffSets(a, b []string) (added, removed []string) {
+	inA := make(map[string]bool, len(a))
+	for _, name := range a {
+		inA[name] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, name := range b {
+		inB[name] = true
+		if !inA[name] {
+			added = append(added, name)
+		}
+	}
+	for _, name := range a {
+		if !inB[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
 }
 
-// GetDocumentsByCategory retrieves all documents by category
-func (s *DocumentService) GetDocumentsByCategory(category string) ([]model.Document, error) {
-	return s.repo.GetByCategory(category)
+func equalIDs(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Kind classifies a domain error by what went wrong, independent of the
+// transport reporting it
+type Kind string
+
+const (
+	// KindNotFound is returned for resources that don't exist or that the
+	// actor may not see
+	KindNotFound Kind = "not_found"
+	// KindConflict is returned when the current state of a resource does
+	// not allow the change
+	KindConflict Kind = "conflict"
+	// KindValidation is returned for input that breaks the domain's rules
+	KindValidation Kind = "validation"
+	// KindForbidden is returned when the actor may not perform an operation
+	KindForbidden Kind = "forbidden"
+	// KindDuplicate is returned when a resource would clash with an
+	// existing one
+	KindDuplicate Kind = "duplicate"
+	// KindUnauthorized is returned when the actor couldn't be identified
+	KindUnauthorized Kind = "unauthorized"
+)
+
+// Error is a domain error. Code is a stable, machine readable identifier
+// such as "document_not_found"; Message is meant for people.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a domain error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// NotFound returns a not found error
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+// Conflict returns a conflict error
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// Invalid returns a validation error
+func Invalid(code, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+// Forbidden returns a forbidden error
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+// Duplicate returns a duplicate error
+func Duplicate(code, message string) *Error {
+	return &Error{Kind: KindDuplicate, Code: code, Message: message}
+}
+
+// Unauthorized returns an unauthorized error
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+// notFound turns a missing record into a not found error for the resource,
+// such as "document" or "document version", and passes any other error
+// through
+func notFound(err error, resource string) error {
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return &Error{
+		Kind:    KindNotFound,
+		Code:    strings.ReplaceAll(resource, " ", "_") + "_not_found",
+		Message: resource + " not found",
+		Err:     err,
+	}
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+	"techdocs/pkg/logger"
+	"techdocs/pkg/mention"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidDigest is returned for an unknown digest preference
+var ErrInvalidDigest = Invalid("invalid_digest", "digest must be immediate or daily")
+
+// NotificationList is a page of notifications along with the number of
+// unread ones
+type NotificationList struct {
+	*repository.Page[model.Notification]
+	Unread int64 `json:"unread"`
+}
+
+type NotificationService struct {
+	repo             *repository.NotificationRepository
+	subscriptionRepo *repository.SubscriptionRepository
+	userRepo         *repository.UserRepository
+	documentRepo     *repository.DocumentRepository
+	roleRepo         *repository.RoleRepository
+}
+
+func NewNotificationService(
+	repo *repository.NotificationRepository,
+	subscriptionRepo *repository.SubscriptionRepository,
+	userRepo *repository.UserRepository,
+	documentRepo *repository.DocumentRepository,
+	roleRepo *repository.RoleRepository,
+) *NotificationService {
+	return &NotificationService{
+		repo:             repo,
+		subscriptionRepo: subscriptionRepo,
+		userRepo:         userRepo,
+		documentRepo:     documentRepo,
+		roleRepo:         roleRepo,
+	}
+}
+
+// NotifyDocumentMentions notifies the users newly @mentioned in a
+// document's content. previous is the content before the change, empty for
+// a new document.
+func (s *NotificationService) NotifyDocumentMentions(doc *model.Document, previous string, actor Actor) {
+	usernames := mention.Added(previous, doc.Content)
+	if len(usernames) == 0 {
+		return
+	}
+	s.notify(doc, nil, s.mentionedUsers(usernames), model.NotificationMention, "%s mentioned you in %q", false, actor)
+}
+
+// NotifyPublishedMentions notifies the users @mentioned in a document since
+// its previous published version who may not see its drafts, once it is
+// published. previous is the content of that version, empty for a first
+// publication. Users who may see drafts were notified as the mentions were
+// written.
+func (s *NotificationService) NotifyPublishedMentions(doc *model.Document, previous string, actor Actor) {
+	usernames := mention.Added(previous, doc.Content)
+	if len(usernames) == 0 {
+		return
+	}
+
+	var userIDs []uint
+	for _, userID := range s.mentionedUsers(usernames) {
+		viewer, err := s.viewer(userID)
+		if err != nil {
+			logger.Error("Failed to get permissions of user ID %d: %v", userID, err)
+			continue
+		}
+		drafts, err := s.documentRepo.HasDraftAccess(doc.ID, viewer)
+		if err != nil {
+			logger.Error("Failed to check draft access of user ID %d to document ID %d: %v", userID, doc.ID, err)
+			continue
+		}
+		if !drafts {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	s.notify(doc, nil, userIDs, model.NotificationMention, "%s mentioned you in %q", false, actor)
+}
+
+// NotifyCommentMentions notifies the users newly @mentioned in a comment.
+// previous is the comment's content before an edit, empty for a new one.
+func (s *NotificationService) NotifyCommentMentions(doc *model.Document, comment *model.Comment, previous string, actor Actor) {
+	usernames := mention.Added(previous, comment.Content)
+	if len(usernames) == 0 {
+		return
+	}
+	commentID := comment.ID
+	s.notify(doc, &commentID, s.mentionedUsers(usernames), model.NotificationMention, "%s mentioned you in a comment on %q", true, actor)
+}
+
+// mentionedUsers looks up the IDs of mentioned users, skipping unknown
+// usernames
+func (s *NotificationService) mentionedUsers(usernames []string) []uint {
+	var userIDs []uint
+	for _, username := range usernames {
+		user, err := s.userRepo.FindByUsername(username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			logger.Error("Failed to look up mentioned user %s: %v", username, err)
+			continue
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+	return userIDs
+}
+
+// subscriptionMessages describe document events to subscribers
+var subscriptionMessages = map[string]string{
+	model.NotificationDocumentCreated:   "%s created %q",
+	model.NotificationDocumentUpdated:   "%s updated %q",
+	model.NotificationDocumentDeleted:   "%s deleted %q",
+	model.NotificationComment:           "%s commented on %q",
+	model.NotificationDocumentPublished: "%s published %q",
+}
+
+// publicEvents are the subscription events that concern readers who only
+// see the published version of a document being redrafted
+var publicEvents = map[string]bool{
+	model.NotificationDocumentDeleted: true,
+	model.NotificationComment:         true,
+}
+
+// NotifySubscribers notifies the users subscribed to a document, its
+// service or one of its tags about an event. commentID is only set for
+// comments.
+func (s *NotificationService) NotifySubscribers(doc *model.Document, event string, commentID *uint, actor Actor) {
+	tags := make([]string, 0, len(doc.Tags))
+	for _, tag := range doc.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	userIDs, err := s.subscriptionRepo.GetSubscribers(doc.ID, doc.ServiceID, tags)
+	if err != nil {
+		logger.Error("Failed to get subscribers of document ID %d: %v", doc.ID, err)
+		return
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	s.notify(doc, commentID, userIDs, event, subscriptionMessages[event], publicEvents[event], actor)
+}
+
+// reviewMessages describe review events to reviewers and authors
+var reviewMessages = map[string]string{
+	model.NotificationReviewRequested:  "%s asked you to review %q",
+	model.NotificationReviewApproved:   "%s approved %q",
+	model.NotificationChangesRequested: "%s requested changes to %q",
+}
+
+// NotifyReview notifies users about a review event: reviewers when their
+// review is requested, the author when a reviewer decided
+func (s *NotificationService) NotifyReview(doc *model.Document, event string, userIDs []uint, actor Actor) {
+	if len(userIDs) == 0 {
+		return
+	}
+	s.notify(doc, nil, userIDs, event, reviewMessages[event], false, actor)
+}
+
+// notify creates a notification for each user who may see the document,
+// except the actor. The message is rendered from format with the actor's
+// name and the document's title. Users who only see the published version
+// of a document being redrafted are left out, unless public says the event
+// concerns them too; then they are told under the published title, so
+// drafts don't leak. The change being announced has been saved already, so
+// failures are only logged.
+func (s *NotificationService) notify(doc *model.Document, commentID *uint, userIDs []uint, kind, format string, public bool, actor Actor) {
+	if len(userIDs) == 0 {
+		return
+	}
+	name := s.actorName(actor)
+	message := fmt.Sprintf(format, name, doc.Title)
+	publishedMessage := ""
+
+	var notifications []model.Notification
+	for _, userID := range userIDs {
+		if userID == actor.UserID {
+			continue
+		}
+
+		current, published, err := s.canSee(userID, doc)
+		if err != nil {
+			logger.Error("Failed to check access of user ID %d to document ID %d: %v", userID, doc.ID, err)
+			continue
+		}
+		text := message
+		if !current {
+			if !published || !public {
+				logger.Info("Not notifying user ID %d who can't see document ID %d as it is", userID, doc.ID)
+				continue
+			}
+			if publishedMessage == "" {
+				version, err := s.documentRepo.GetVersion(doc.ID, *doc.PublishedVersion)
+				if err != nil {
+					logger.Error("Failed to get published version of document ID %d: %v", doc.ID, err)
+					continue
+				}
+				publishedMessage = fmt.Sprintf(format, name, version.Title)
+			}
+			text = publishedMessage
+		}
+
+		documentID := doc.ID
+		notifications = append(notifications, model.Notification{
+			UserID:     userID,
+			ActorID:    actor.UserID,
+			Type:       kind,
+			Message:    text,
+			DocumentID: &documentID,
+			CommentID:  commentID,
+		})
+	}
+
+	if err := s.repo.Create(notifications); err != nil {
+		logger.Error("Failed to create %s notifications for document ID %d: %v", kind, doc.ID, err)
+	}
+}
+
+// canSee reports whether a user may see a document as it is stored, so
+// notifications don't leak restricted documents or drafts. For a published
+// document being redrafted, published reports whether a user who can't see
+// the draft may see the published version.
+func (s *NotificationService) canSee(userID uint, doc *model.Document) (current, published bool, err error) {
+	live := doc.Status == model.StatusPublished || doc.Status == model.StatusArchived
+	if !doc.Restricted && live {
+		return true, true, nil
+	}
+	viewer, err := s.viewer(userID)
+	if err != nil {
+		return false, false, err
+	}
+	if current, err = s.documentRepo.IsCurrentVisible(doc.ID, viewer); err != nil || current {
+		return current, current, err
+	}
+	if doc.PublishedVersion == nil {
+		return false, false, nil
+	}
+	published, err = s.documentRepo.IsVisible(doc.ID, viewer)
+	return false, published, err
+}
+
+// viewer returns what a user may see, based on their permissions
+func (s *NotificationService) viewer(userID uint) (repository.Viewer, error) {
+	permissions, err := s.roleRepo.GetUserPermissions(userID)
+	if err != nil {
+		return repository.Viewer{}, err
+	}
+	return Actor{UserID: userID, Permissions: permissions}.Viewer(), nil
+}
+
+func (s *NotificationService) actorName(actor Actor) string {
+	user, err := s.userRepo.FindByID(actor.UserID)
+	if err != nil {
+		return "Someone"
+	}
+	return user.Username
+}
+
+// GetNotifications retrieves one page of the actor's notifications and
+// their unread count
+func (s *NotificationService) GetNotifications(actor Actor, q repository.ListQuery) (*NotificationList, error) {
+	page, err := s.repo.List(actor.UserID, q)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.repo.CountUnread(actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &NotificationList{Page: page, Unread: unread}, nil
+}
+
+// MarkRead marks one of the actor's notifications as read
+func (s *NotificationService) MarkRead(id uint, actor Actor) error {
+	return notFound(s.repo.MarkRead(actor.UserID, id), "notification")
+}
+
+// MarkAllRead marks all of the actor's notifications as read
+func (s *NotificationService) MarkAllRead(actor Actor) (int64, error) {
+	return s.repo.MarkAllRead(actor.UserID)
+}
+
+// GetDigest returns the actor's digest preference
+func (s *NotificationService) GetDigest(actor Actor) (string, error) {
+	user, err := s.userRepo.FindByID(actor.UserID)
+	if err != nil {
+		return "", notFound(err, "user")
+	}
+	return user.Digest, nil
+}
+
+// SetDigest sets the actor's digest preference to immediate or daily
+func (s *NotificationService) SetDigest(digest string, actor Actor) error {
+	if digest != model.DigestImmediate && digest != model.DigestDaily {
+		return ErrInvalidDigest
+	}
+	return s.userRepo.UpdateDigest(actor.UserID, digest)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+)
+
+// ErrUnknownPermission is returned when a role references a permission
+// that doesn't exist
+var ErrUnknownPermission = Invalid("unknown_permission", "unknown permission")
+
+// ErrRoleExists is returned when a role with the same name already exists
+var ErrRoleExists = Duplicate("role_exists", "a role with this name already exists")
+
+// ErrRoleInUse is returned when deleting a role that users still hold
+var ErrRoleInUse = Conflict("role_in_use", "role is still assigned to users")
+
+type RoleService struct {
+	repo     *repository.RoleRepository
+	userRepo *repository.UserRepository
+}
+
+func NewRoleService(repo *repository.RoleRepository, userRepo *repository.UserRepository) *RoleService {
+	return &RoleService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+// PermissionsForUser returns the names of the permissions a user holds
+// through their role. It is used by the permission middleware.
+func (s *RoleService) PermissionsForUser(userID uint) ([]string, error) {
+	return s.repo.GetUserPermissions(userID)
+}
+
+// CreateRole creates a role bundling the named permissions
+func (s *RoleService) CreateRole(name, description string, permissions []string) (*model.Role, error) {
+	taken, err := s.repo.Taken(name)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrRoleExists
+	}
+	granted, err := s.lookupPermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &model.Role{Name: name, Description: description, Permissions: granted}
+	if err := s.repo.Create(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// UpdateRole changes a role's description and replaces its permissions
+func (s *RoleService) UpdateRole(id uint, description string, permissions []string) (*model.Role, error) {
+	role, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err, "role")
+	}
+	granted, err := s.lookupPermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Description = description
+	role.Permissions = granted
+	if err := s.repo.Update(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// DeleteRole deletes a role no user holds anymore
+func (s *RoleService) DeleteRole(id uint) error {
+	role, err := s.repo.GetByID(id)
+	if err != nil {
+		return notFound(err, "role")
+	}
+
+	holders, err := s.repo.CountUsers(role.Name)
+	if err != nil {
+		return err
+	}
+	if holders > 0 {
+		return ErrRoleInUse
+	}
+	return s.repo.Delete(role)
+}
+
+// GetAllRoles retrieves all roles with their permissions
+func (s *RoleService) GetAllRoles() ([]model.Role, error) {
+	return s.repo.GetAll()
+}
+
+// GetAllPermissions retrieves all known permissions
+func (s *RoleService) GetAllPermissions() ([]model.Permission, error) {
+	return s.repo.GetPermissions()
+}
+
+// AssignRole gives a user a role. The change applies to the user's next
+// request, permissions are looked up on every request.
+func (s *RoleService) AssignRole(userID uint, roleName string) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return notFound(err, "user")
+	}
+	if _, err := s.repo.GetByName(roleName); err != nil {
+		return notFound(err, "role")
+	}
+	return s.userRepo.UpdateRole(userID, roleName)
+}
+
+// lookupPermissions loads the named permissions, failing on unknown names
+func (s *RoleService) lookupPermissions(names []string) ([]model.Permission, error) {
+	if len(names) == 0 {
+		return []model.Permission{}, nil
+	}
+
+	permissions, err := s.repo.GetPermissionsByName(names)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		found[p.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			return nil, ErrUnknownPermission
+		}
+	}
+	return permissions, nil
+}
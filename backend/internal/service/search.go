@@ -0,0 +1,209 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"techdocs/internal/repository"
+	"techdocs/pkg/search"
+)
+
+// Kinds of search results
+const (
+	SearchTypeDocument = "document"
+	SearchTypeService  = "service"
+	SearchTypeComment  = "comment"
+)
+
+// ErrEmptyQuery is returned for a search without terms
+var ErrEmptyQuery = Invalid("empty_query", "search query must not be empty")
+
+// SearchResult is a single ranked match. DocumentID is set for comments,
+// pointing at the document they belong to.
+type SearchResult struct {
+	Type       string   `json:"type"`
+	ID         uint     `json:"id"`
+	DocumentID uint     `json:"document_id,omitempty"`
+	Title      string   `json:"title"`
+	Snippet    string   `json:"snippet"`
+	Tags       []string `json:"tags,omitempty"`
+	Score      float64  `json:"score"`
+}
+
+// SearchResponse holds the results of a search, best match first. Count
+// is the number of results returned, which the limit caps; it isn't the
+// number of matches.
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Count   int            `json:"count"`
+	Results []SearchResult `json:"results"`
+}
+
+type SearchService struct {
+	repo *repository.SearchRepository
+}
+
+func NewSearchService(repo *repository.SearchRepository) *SearchService {
+	return &SearchService{repo: repo}
+}
+
+// Search looks for the query in documents, services and comments. types
+// limits the kinds of results, all kinds are searched when it is empty.
+func (s *SearchService) Search(query string, types []string, limit int, actor Actor) (*SearchResponse, error) {
+	query = strings.TrimSpace(query)
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	wanted := func(t string) bool {
+		if len(types) == 0 {
+			return true
+		}
+		for _, w := range types {
+			if w == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	var results []SearchResult
+	if wanted(SearchTypeDocument) {
+		found, err := s.searchDocuments(query, terms, limit, actor)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+	if wanted(SearchTypeService) {
+		found, err := s.searchServices(query, terms, limit)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+	if wanted(SearchTypeComment) {
+		found, err := s.searchComments(query, terms, limit, actor)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	if results == nil {
+		results = []SearchResult{}
+	}
+
+	return &SearchResponse{
+		Query:   query,
+		Count:   len(results),
+		Results: results,
+	}, nil
+}
+
+func (s *SearchService) searchDocuments(query string, terms []string, limit int, actor Actor) ([]SearchResult, error) {
+	hits, err := s.repo.SearchDocuments(query, actor.Viewer(), limit)
+	if err != nil {
+		return nil, err
+	}
+	documents, err := s.repo.GetDocuments(hitIDs(hits), actor.Viewer())
+	if err != nil {
+		return nil, err
+	}
+
+	scores := hitScores(hits)
+	results := make([]SearchResult, 0, len(documents))
+	for _, doc := range documents {
+		tags := make([]string, 0, len(doc.Tags))
+		for _, tag := range doc.Tags {
+			tags = append(tags, tag.Name)
+		}
+
+		// Prefer the description for the snippet when it mentions a term
+		text := doc.Content
+		if doc.Description != "" && strings.Contains(search.Snippet(doc.Description, terms), "<mark>") {
+			text = doc.Description
+		}
+
+		results = append(results, SearchResult{
+			Type:    SearchTypeDocument,
+			ID:      doc.ID,
+			Title:   search.Snippet(doc.Title, terms),
+			Snippet: search.Snippet(text, terms),
+			Tags:    tags,
+			Score:   scores[doc.ID],
+		})
+	}
+	return results, nil
+}
+
+func (s *SearchService) searchServices(query string, terms []string, limit int) ([]SearchResult, error) {
+	hits, err := s.repo.SearchServices(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	services, err := s.repo.GetServices(hitIDs(hits))
+	if err != nil {
+		return nil, err
+	}
+
+	scores := hitScores(hits)
+	results := make([]SearchResult, 0, len(services))
+	for _, svc := range services {
+		results = append(results, SearchResult{
+			Type:    SearchTypeService,
+			ID:      svc.ID,
+			Title:   search.Snippet(svc.Name, terms),
+			Snippet: search.Snippet(svc.Name, terms),
+			Score:   scores[svc.ID],
+		})
+	}
+	return results, nil
+}
+
+func (s *SearchService) searchComments(query string, terms []string, limit int, actor Actor) ([]SearchResult, error) {
+	hits, err := s.repo.SearchComments(query, actor.Viewer(), limit)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.repo.GetComments(hitIDs(hits), actor.Viewer())
+	if err != nil {
+		return nil, err
+	}
+
+	scores := hitScores(hits)
+	results := make([]SearchResult, 0, len(comments))
+	for _, comment := range comments {
+		results = append(results, SearchResult{
+			Type:       SearchTypeComment,
+			ID:         comment.ID,
+			DocumentID: comment.DocumentID,
+			Title:      search.Snippet(comment.Document.Title, terms),
+			Snippet:    search.Snippet(comment.Content, terms),
+			Score:      scores[comment.ID],
+		})
+	}
+	return results, nil
+}
+
+func hitIDs(hits []repository.SearchHit) []uint {
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func hitScores(hits []repository.SearchHit) map[uint]float64 {
+	scores := make(map[uint]float64, len(hits))
+	for _, hit := range hits {
+		scores[hit.ID] = hit.Score
+	}
+	return scores
+}
@@ -6,39 +6,101 @@ import (
 )
 
 type ServiceService struct {
-	repo *repository.ServiceRepository
+	repo         *repository.ServiceRepository
+	teamRepo     *repository.TeamRepository
+	categoryRepo *repository.CategoryRepository
+	webhooks     *WebhookService
 }
 
-func NewServiceService(repo *repository.ServiceRepository) *ServiceService {
-	return &ServiceService{repo: repo}
+func NewServiceService(repo *repository.ServiceRepository, teamRepo *repository.TeamRepository, categoryRepo *repository.CategoryRepository, webhooks *WebhookService) *ServiceService {
+	return &ServiceService{
+		repo:         repo,
+		teamRepo:     teamRepo,
+		categoryRepo: categoryRepo,
+		webhooks:     webhooks,
+	}
 }
 
-// CreateService creates a new service
-func (s *ServiceService) CreateService(service *model.Service) error {
-	return s.repo.Create(service)
+// CreateService creates a new service. Only members of a team may make it
+// the service's owner.
+func (s *ServiceService) CreateService(service *model.Service, actor Actor) error {
+	if err := requireTeamMember(s.teamRepo, actor, service.TeamID); err != nil {
+		return err
+	}
+	if err := requireCategory(s.categoryRepo, service.CategoryID); err != nil {
+		return err
+	}
+	if err := s.repo.Create(service); err != nil {
+		return err
+	}
+	if err := s.reload(service); err != nil {
+		return err
+	}
+
+	s.webhooks.DispatchService(model.EventServiceCreated, service, actor)
+	return nil
 }
 
 // UpdateService updates an existing service
-func (s *ServiceService) UpdateService(service *model.Service) error {
-	return s.repo.Update(service)
+func (s *ServiceService) UpdateService(service *model.Service, actor Actor) error {
+	existing, err := s.repo.GetByID(service.ID)
+	if err != nil {
+		return notFound(err, "service")
+	}
+	if !equalIDs(service.TeamID, existing.TeamID) {
+		if err := requireTeamMember(s.teamRepo, actor, service.TeamID); err != nil {
+			return err
+		}
+	}
+	if err := requireCategory(s.categoryRepo, service.CategoryID); err != nil {
+		return err
+	}
+	if err := s.repo.Update(service); err != nil {
+		return err
+	}
+	if err := s.reload(service); err != nil {
+		return err
+	}
+
+	s.webhooks.DispatchService(model.EventServiceUpdated, service, actor)
+	return nil
+}
+
+// reload replaces a freshly written service with the stored one, so it
+// carries its team and category
+func (s *ServiceService) reload(service *model.Service) error {
+	stored, err := s.repo.GetByID(service.ID)
+	if err != nil {
+		return err
+	}
+	*service = *stored
+	return nil
 }
 
 // DeleteService deletes a service
-func (s *ServiceService) DeleteService(id uint) error {
-	return s.repo.Delete(id)
+func (s *ServiceService) DeleteService(id uint, actor Actor) error {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return notFound(err, "service")
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.webhooks.DispatchService(model.EventServiceDeleted, existing, actor)
+	return nil
 }
 
 // GetServiceByID retrieves a service by its ID
 func (s *ServiceService) GetServiceByID(id uint) (*model.Service, error) {
-	return s.repo.GetByID(id)
-}
-
-// GetAllServices retrieves all services
-func (s *ServiceService) GetAllServices() ([]model.Service, error) {
-	return s.repo.GetAll()
+	service, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err, "service")
+	}
+	return service, nil
 }
 
-// GetServicesByCategory retrieves all services by category
-func (s *ServiceService) GetServicesByCategory(category string) ([]model.Service, error) {
-	return s.repo.GetByCategory(category)
+// GetAllServices retrieves one page of services
+func (s *ServiceService) GetAllServices(q repository.ListQuery) (*repository.Page[model.Service], error) {
+	return s.repo.List(q)
 }
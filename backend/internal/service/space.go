@@ -0,0 +1,230 @@
+package service
+
+import (
+	"errors"
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidSpace is returned when a document is placed in an unknown
+	// space
+	ErrInvalidSpace = Invalid("invalid_space", "space not found")
+	// ErrInvalidParentPage is returned when a document is placed below a
+	// page that doesn't exist, that the actor may not see or that lies in
+	// another space
+	ErrInvalidParentPage = Invalid("invalid_parent_page", "parent page not found or in another space")
+	// ErrSpaceExists is returned when another space already uses a name
+	ErrSpaceExists = Duplicate("space_exists", "a space with this name already exists")
+)
+
+// PageNode is a page in a space's tree along with the pages below it
+type PageNode struct {
+	ID       uint        `json:"id"`
+	Title    string      `json:"title"`
+	Status   string      `json:"status"`
+	Position int         `json:"position"`
+	Children []*PageNode `json:"children"`
+}
+
+// Breadcrumb is a page on the way from the top of a tree down to a document
+type Breadcrumb struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+}
+
+type SpaceService struct {
+	repo         *repository.SpaceRepository
+	documentRepo *repository.DocumentRepository
+	teamRepo     *repository.TeamRepository
+}
+
+func NewSpaceService(repo *repository.SpaceRepository, documentRepo *repository.DocumentRepository, teamRepo *repository.TeamRepository) *SpaceService {
+	return &SpaceService{
+		repo:         repo,
+		documentRepo: documentRepo,
+		teamRepo:     teamRepo,
+	}
+}
+
+// CreateSpace creates a new space. Only members of a team may make it the
+// space's owner.
+func (s *SpaceService) CreateSpace(space *model.Space, actor Actor) error {
+	if err := requireTeamMember(s.teamRepo, actor, space.TeamID); err != nil {
+		return err
+	}
+	if err := s.requireFreeName(space); err != nil {
+		return err
+	}
+	if err := s.repo.Create(space); err != nil {
+		return err
+	}
+	return s.reload(space)
+}
+
+// UpdateSpace updates an existing space
+func (s *SpaceService) UpdateSpace(space *model.Space, actor Actor) error {
+	existing, err := s.repo.GetByID(space.ID)
+	if err != nil {
+		return notFound(err, "space")
+	}
+	if !equalIDs(space.TeamID, existing.TeamID) {
+		if err := requireTeamMember(s.teamRepo, actor, space.TeamID); err != nil {
+			return err
+		}
+	}
+	if err := s.requireFreeName(space); err != nil {
+		return err
+	}
+	if err := s.repo.Update(space); err != nil {
+		return err
+	}
+	return s.reload(space)
+}
+
+// reload replaces a freshly written space with the stored one, so it
+// carries its team
+func (s *SpaceService) reload(space *model.Space) error {
+	stored, err := s.repo.GetByID(space.ID)
+	if err != nil {
+		return err
+	}
+	*space = *stored
+	return nil
+}
+
+// requireFreeName checks that no other space uses a space's name
+func (s *SpaceService) requireFreeName(space *model.Space) error {
+	taken, err := s.repo.Taken(space.Name, space.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrSpaceExists
+	}
+	return nil
+}
+
+// DeleteSpace deletes a space; its pages are kept outside of any space
+func (s *SpaceService) DeleteSpace(id uint) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return notFound(err, "space")
+	}
+	return s.repo.Delete(id)
+}
+
+// GetSpaceByID retrieves a space by its ID
+func (s *SpaceService) GetSpaceByID(id uint) (*model.Space, error) {
+	space, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err, "space")
+	}
+	return space, nil
+}
+
+// GetAllSpaces retrieves one page of spaces
+func (s *SpaceService) GetAllSpaces(q repository.ListQuery) (*repository.Page[model.Space], error) {
+	return s.repo.List(q)
+}
+
+// GetTree retrieves the pages of a space the actor may see, arranged as
+// trees. Pages whose parent the actor may not see are shown at the top.
+func (s *SpaceService) GetTree(spaceID uint, actor Actor) ([]*PageNode, error) {
+	if _, err := s.repo.GetByID(spaceID); err != nil {
+		return nil, notFound(err, "space")
+	}
+	pages, err := s.documentRepo.ListInSpace(spaceID, actor.Viewer())
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*PageNode, len(pages))
+	for _, page := range pages {
+		nodes[page.ID] = &PageNode{
+			ID:       page.ID,
+			Title:    page.Title,
+			Status:   page.Status,
+			Position: page.Position,
+			Children: []*PageNode{},
+		}
+	}
+
+	roots := []*PageNode{}
+	for _, page := range pages {
+		node := nodes[page.ID]
+		if page.ParentID != nil {
+			if parent, ok := nodes[*page.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots, nil
+}
+
+// MoveDocument places a document in a space, below a parent page and at a
+// position among its siblings. A parent alone puts the document in the
+// parent's space and a nil position puts it last.
+func (s *DocumentService) MoveDocument(id uint, spaceID, parentID *uint, position *int, actor Actor) (*model.Document, error) {
+	doc, err := s.repo.GetByID(id, actor.Viewer())
+	if err != nil {
+		return nil, notFound(err, "document")
+	}
+	if err := s.policy.CanModify(actor, doc, "move"); err != nil {
+		return nil, err
+	}
+	spaceID, err = s.placement(spaceID, parentID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Move(id, spaceID, parentID, position); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id, actor.Viewer())
+}
+
+// placement checks where a document is about to be placed and returns its
+// space, which is the parent's when only a parent is given
+func (s *DocumentService) placement(spaceID, parentID *uint, actor Actor) (*uint, error) {
+	if parentID != nil {
+		parent, err := s.repo.GetByID(*parentID, actor.Viewer())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidParentPage
+		}
+		if err != nil {
+			return nil, err
+		}
+		if spaceID == nil {
+			spaceID = parent.SpaceID
+		} else if !equalIDs(spaceID, parent.SpaceID) {
+			return nil, ErrInvalidParentPage
+		}
+	}
+
+	if spaceID != nil {
+		_, err := s.spaceRepo.GetByID(*spaceID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidSpace
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return spaceID, nil
+}
+
+// breadcrumbs returns the trail of pages above a document
+func (s *DocumentService) breadcrumbs(doc *model.Document, actor Actor) ([]Breadcrumb, error) {
+	ancestors, err := s.repo.Ancestors(doc, actor.Viewer())
+	if err != nil {
+		return nil, err
+	}
+	trail := make([]Breadcrumb, len(ancestors))
+	for i, page := range ancestors {
+		trail[i] = Breadcrumb{ID: page.ID, Title: page.Title}
+	}
+	return trail, nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+)
+
+// ErrInvalidSubscriptionTarget is returned for an unknown target type
+var ErrInvalidSubscriptionTarget = Invalid("invalid_subscription_target", "target type must be document, service or tag")
+
+type SubscriptionService struct {
+	repo         *repository.SubscriptionRepository
+	documentRepo *repository.DocumentRepository
+	serviceRepo  *repository.ServiceRepository
+	tagRepo      *repository.TagRepository
+}
+
+func NewSubscriptionService(
+	repo *repository.SubscriptionRepository,
+	documentRepo *repository.DocumentRepository,
+	serviceRepo *repository.ServiceRepository,
+	tagRepo *repository.TagRepository,
+) *SubscriptionService {
+	return &SubscriptionService{
+		repo:         repo,
+		documentRepo: documentRepo,
+		serviceRepo:  serviceRepo,
+		tagRepo:      tagRepo,
+	}
+}
+
+// Subscribe subscribes the actor to a document they may see, a service or
+// a tag. Subscribing twice to the same target returns the existing
+// subscription.
+func (s *SubscriptionService) Subscribe(targetType string, targetID uint, actor Actor) (*model.Subscription, error) {
+	var err error
+	switch targetType {
+	case model.SubscriptionDocument:
+		_, err = s.documentRepo.GetByID(targetID, actor.Viewer())
+	case model.SubscriptionService:
+		_, err = s.serviceRepo.GetByID(targetID)
+	case model.SubscriptionTag:
+		_, err = s.tagRepo.GetByID(targetID)
+	default:
+		return nil, ErrInvalidSubscriptionTarget
+	}
+	if err != nil {
+		return nil, notFound(err, targetType)
+	}
+
+	subscription := &model.Subscription{
+		UserID:     actor.UserID,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+	if err := s.repo.FindOrCreate(subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Unsubscribe deletes one of the actor's subscriptions
+func (s *SubscriptionService) Unsubscribe(id uint, actor Actor) error {
+	return notFound(s.repo.Delete(actor.UserID, id), "subscription")
+}
+
+// GetSubscriptions retrieves one page of the actor's subscriptions
+func (s *SubscriptionService) GetSubscriptions(actor Actor, q repository.ListQuery) (*repository.Page[model.Subscription], error) {
+	return s.repo.List(actor.UserID, q)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidTagName is returned when a tag is renamed to a blank name
+	ErrInvalidTagName = Invalid("invalid_tag_name", "tag name must not be empty")
+	// ErrTagExists is returned when a tag is renamed to the name of
+	// another tag
+	ErrTagExists = Duplicate("tag_exists", "another tag already has this name, merge the tags instead")
+	// ErrInvalidMerge is returned when a tag is merged into itself or into
+	// a tag that doesn't exist
+	ErrInvalidMerge = Invalid("invalid_merge", "tags can only be merged into another existing tag")
+)
+
+// TagSummary is a tag along with how many documents the viewer may see
+// carry it
+type TagSummary struct {
+	model.Tag
+	DocumentCount int64 `json:"document_count"`
+}
+
+type TagService struct {
+	repo         *repository.TagRepository
+	documentRepo *repository.DocumentRepository
+}
+
+func NewTagService(repo *repository.TagRepository, documentRepo *repository.DocumentRepository) *TagService {
+	return &TagService{
+		repo:         repo,
+		documentRepo: documentRepo,
+	}
+}
+
+// GetAllTags retrieves one page of tags with their document counts. A
+// prefix narrows the list down to the tags starting with it, for
+// autocompletion.
+func (s *TagService) GetAllTags(prefix string, actor Actor, q repository.ListQuery) (*repository.Page[TagSummary], error) {
+	page, err := s.repo.List(strings.TrimSpace(prefix), q)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(page.Items))
+	for i, tag := range page.Items {
+		ids[i] = tag.ID
+	}
+	counts, err := s.repo.CountDocuments(ids, actor.Viewer())
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]TagSummary, len(page.Items))
+	for i, tag := range page.Items {
+		summaries[i] = TagSummary{Tag: tag, DocumentCount: counts[tag.ID]}
+	}
+	return &repository.Page[TagSummary]{
+		Items:      summaries,
+		Total:      page.Total,
+		Page:       page.Page,
+		Limit:      page.Limit,
+		NextCursor: page.NextCursor,
+	}, nil
+}
+
+// GetTagDocuments retrieves one page of the documents the actor may see
+// that carry a tag
+func (s *TagService) GetTagDocuments(name string, actor Actor, q repository.ListQuery) (*repository.Page[model.Document], error) {
+	tag, err := s.repo.GetByName(name)
+	if err != nil {
+		return nil, notFound(err, "tag")
+	}
+	q.Filter.Tag = tag.Name
+	return s.documentRepo.List(actor.Viewer(), q)
+}
+
+// RenameTag gives a tag a new name. Changing only the case of a name is
+// allowed, taking the name of another tag is not.
+func (s *TagService) RenameTag(name, newName string) (*model.Tag, error) {
+	tag, err := s.repo.GetByName(name)
+	if err != nil {
+		return nil, notFound(err, "tag")
+	}
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return nil, ErrInvalidTagName
+	}
+
+	taken, err := s.repo.Taken(newName, tag.ID)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrTagExists
+	}
+
+	if err := s.repo.Rename(tag, newName); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// MergeTags folds a tag into another one, which the documents and
+// subscriptions of the first then carry instead. The merged tag is gone
+// afterwards.
+func (s *TagService) MergeTags(name, into string) (*model.Tag, error) {
+	source, err := s.repo.GetByName(name)
+	if err != nil {
+		return nil, notFound(err, "tag")
+	}
+	target, err := s.repo.GetByName(strings.TrimSpace(into))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidMerge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if target.ID == source.ID {
+		return nil, ErrInvalidMerge
+	}
+
+	if err := s.repo.Merge(source, target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+	"techdocs/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidTeamRole is returned for an unknown team role
+var ErrInvalidTeamRole = Invalid("invalid_team_role", "team role must be member or admin")
+
+// ErrLastTeamAdmin is returned when a change would leave a team without admins
+var ErrLastTeamAdmin = Conflict("last_team_admin", "a team needs at least one admin")
+
+// ErrTeamExists is returned when another team already uses a name
+var ErrTeamExists = Duplicate("team_exists", "a team with this name already exists")
+
+// ErrInvalidTeam is returned when a document, service or space is given to
+// an unknown team
+var ErrInvalidTeam = Invalid("invalid_team", "team not found")
+
+type TeamService struct {
+	repo     *repository.TeamRepository
+	userRepo *repository.UserRepository
+}
+
+func NewTeamService(repo *repository.TeamRepository, userRepo *repository.UserRepository) *TeamService {
+	return &TeamService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+// CreateTeam creates a new team, making the actor its first admin
+func (s *TeamService) CreateTeam(team *model.Team, actor Actor) error {
+	if err := s.requireFreeName(team); err != nil {
+		return err
+	}
+	return s.repo.Create(team, actor.UserID)
+}
+
+// UpdateTeam updates a team's name and description
+func (s *TeamService) UpdateTeam(team *model.Team, actor Actor) error {
+	if _, err := s.repo.GetByID(team.ID); err != nil {
+		return notFound(err, "team")
+	}
+	if err := s.requireTeamAdmin(actor, team.ID, "update"); err != nil {
+		return err
+	}
+	if err := s.requireFreeName(team); err != nil {
+		return err
+	}
+	return s.repo.Update(team)
+}
+
+// requireFreeName checks that no other team uses a team's name
+func (s *TeamService) requireFreeName(team *model.Team) error {
+	taken, err := s.repo.Taken(team.Name, team.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrTeamExists
+	}
+	return nil
+}
+
+// DeleteTeam deletes a team; documents and services it owned are kept
+func (s *TeamService) DeleteTeam(id uint, actor Actor) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return notFound(err, "team")
+	}
+	if err := s.requireTeamAdmin(actor, id, "delete"); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// GetTeamByID retrieves a team with its members
+func (s *TeamService) GetTeamByID(id uint) (*model.Team, error) {
+	team, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err, "team")
+	}
+	return team, nil
+}
+
+// GetAllTeams retrieves one page of teams
+func (s *TeamService) GetAllTeams(q repository.ListQuery) (*repository.Page[model.Team], error) {
+	return s.repo.List(q)
+}
+
+// GetTeamMembers retrieves the members of a team
+func (s *TeamService) GetTeamMembers(teamID uint) ([]model.TeamMember, error) {
+	if _, err := s.repo.GetByID(teamID); err != nil {
+		return nil, notFound(err, "team")
+	}
+	return s.repo.GetMembers(teamID)
+}
+
+// SetTeamMember adds a user to a team or changes their role
+func (s *TeamService) SetTeamMember(teamID, userID uint, role string, actor Actor) (*model.TeamMember, error) {
+	if role != model.TeamRoleMember && role != model.TeamRoleAdmin {
+		return nil, ErrInvalidTeamRole
+	}
+	if _, err := s.repo.GetByID(teamID); err != nil {
+		return nil, notFound(err, "team")
+	}
+	if err := s.requireTeamAdmin(actor, teamID, "manage members of"); err != nil {
+		return nil, err
+	}
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return nil, notFound(err, "user")
+	}
+	if role != model.TeamRoleAdmin {
+		if err := s.keepOneAdmin(teamID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	member := &model.TeamMember{TeamID: teamID, UserID: userID, Role: role}
+	if err := s.repo.SetMember(member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveTeamMember removes a user from a team. Members may always leave a
+// team themselves.
+func (s *TeamService) RemoveTeamMember(teamID, userID uint, actor Actor) error {
+	if _, err := s.repo.GetByID(teamID); err != nil {
+		return notFound(err, "team")
+	}
+	if actor.UserID != userID {
+		if err := s.requireTeamAdmin(actor, teamID, "manage members of"); err != nil {
+			return err
+		}
+	}
+	if err := s.keepOneAdmin(teamID, userID); err != nil {
+		return err
+	}
+	return s.repo.RemoveMember(teamID, userID)
+}
+
+// keepOneAdmin fails when userID is the only admin left in the team
+func (s *TeamService) keepOneAdmin(teamID, userID uint) error {
+	role, err := s.repo.GetMemberRole(teamID, userID)
+	if err != nil {
+		return err
+	}
+	if role != model.TeamRoleAdmin {
+		return nil
+	}
+
+	admins, err := s.repo.CountAdmins(teamID)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastTeamAdmin
+	}
+	return nil
+}
+
+func (s *TeamService) requireTeamAdmin(actor Actor, teamID uint, action string) error {
+	if actor.Can(model.PermTeamsAdmin) {
+		return nil
+	}
+
+	role, err := s.repo.GetMemberRole(teamID, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if role == model.TeamRoleAdmin {
+		return nil
+	}
+
+	logger.Error("Authorization denied: user ID %d may not %s team ID %d", actor.UserID, action, teamID)
+	return ErrForbidden
+}
+
+// requireTeamMember checks that the actor belongs to the team about to own
+// a document or service. A nil team needs no check.
+func requireTeamMember(teamRepo *repository.TeamRepository, actor Actor, teamID *uint) error {
+	if teamID == nil {
+		return nil
+	}
+	_, err := teamRepo.GetByID(*teamID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidTeam
+	}
+	if err != nil {
+		return err
+	}
+	if actor.Can(model.PermTeamsAdmin) {
+		return nil
+	}
+
+	role, err := teamRepo.GetMemberRole(*teamID, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if role != "" {
+		return nil
+	}
+
+	logger.Error("Authorization denied: user ID %d may not assign ownership to team ID %d", actor.UserID, *teamID)
+	return ErrForbidden
+}
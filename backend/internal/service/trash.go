@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+	"techdocs/pkg/logger"
+	"time"
+)
+
+// purgeInterval is how often deleted documents and services past the
+// retention period are looked for
+const purgeInterval = time.Hour
+
+// TrashService lets users get deleted documents and services back and
+// removes them for good once they have been in the trash long enough
+type TrashService struct {
+	repo      *repository.TrashRepository
+	policy    *DocumentPolicy
+	webhooks  *WebhookService
+	retention time.Duration
+}
+
+// NewTrashService creates the trash service. A retention of zero keeps
+// deleted items until they are purged by hand.
+func NewTrashService(repo *repository.TrashRepository, permissionRepo *repository.PermissionRepository, teamRepo *repository.TeamRepository, webhooks *WebhookService, retention time.Duration) *TrashService {
+	return &TrashService{
+		repo:      repo,
+		policy:    NewDocumentPolicy(permissionRepo, teamRepo),
+		webhooks:  webhooks,
+		retention: retention,
+	}
+}
+
+// GetDeletedDocuments retrieves one page of the deleted documents the
+// actor may see
+func (s *TrashService) GetDeletedDocuments(actor Actor, q repository.ListQuery) (*repository.Page[model.Document], error) {
+	return s.repo.ListDocuments(actor.Viewer(), q)
+}
+
+// GetDeletedServices retrieves one page of the deleted services
+func (s *TrashService) GetDeletedServices(q repository.ListQuery) (*repository.Page[model.Service], error) {
+	return s.repo.ListServices(q)
+}
+
+// RestoreDocument brings back a deleted document, which needs the same
+// access as deleting it
+func (s *TrashService) RestoreDocument(id uint, actor Actor) (*model.Document, error) {
+	doc, err := s.repo.GetDocument(id, actor.Viewer())
+	if err != nil {
+		return nil, notFound(err, "deleted document")
+	}
+	if err := s.policy.CanModify(actor, doc, "restore"); err != nil {
+		return nil, err
+	}
+	if err := s.repo.RestoreDocument(id); err != nil {
+		return nil, err
+	}
+
+	doc.DeletedAt.Valid = false
+	s.webhooks.DispatchDocument(model.EventDocumentRestored, doc, actor)
+	return doc, nil
+}
+
+// RestoreService brings back a deleted service
+func (s *TrashService) RestoreService(id uint, actor Actor) (*model.Service, error) {
+	svc, err := s.repo.GetService(id)
+	if err != nil {
+		return nil, notFound(err, "deleted service")
+	}
+	if err := s.repo.RestoreService(id); err != nil {
+		return nil, err
+	}
+
+	svc.DeletedAt.Valid = false
+	s.webhooks.DispatchService(model.EventServiceRestored, svc, actor)
+	return svc, nil
+}
+
+// PurgeDocument removes a deleted document for good
+func (s *TrashService) PurgeDocument(id uint) error {
+	return notFound(s.repo.PurgeDocument(id), "deleted document")
+}
+
+// PurgeService removes a deleted service for good
+func (s *TrashService) PurgeService(id uint) error {
+	return notFound(s.repo.PurgeService(id), "deleted service")
+}
+
+// Run purges expired items from the trash every hour until ctx is
+// cancelled. It returns right away when there is no retention period.
+func (s *TrashService) Run(ctx context.Context) {
+	if s.retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		s.PurgeExpired(ctx, time.Now().Add(-s.retention))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpired removes every document and service deleted before a time
+func (s *TrashService) PurgeExpired(ctx context.Context, before time.Time) {
+	documents, err := s.repo.ExpiredDocuments(before)
+	if err != nil {
+		logger.Error("Failed to load expired documents in the trash: %v", err)
+	}
+	for _, id := range documents {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.repo.PurgeDocument(id); err != nil {
+			logger.Error("Failed to purge document ID %d: %v", id, err)
+			continue
+		}
+		logger.Info("Purged document ID %d from the trash", id)
+	}
+
+	services, err := s.repo.ExpiredServices(before)
+	if err != nil {
+		logger.Error("Failed to load expired services in the trash: %v", err)
+	}
+	for _, id := range services {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.repo.PurgeService(id); err != nil {
+			logger.Error("Failed to purge service ID %d: %v", id, err)
+			continue
+		}
+		logger.Info("Purged service ID %d from the trash", id)
+	}
+}
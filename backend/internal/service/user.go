@@ -7,6 +7,18 @@ import (
 	"techdocs/pkg/auth"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUsernameTaken is returned when registering a username in use
+	ErrUsernameTaken = Duplicate("username_taken", "username already exists")
+	// ErrEmailTaken is returned when registering or changing to an e-mail
+	// address in use
+	ErrEmailTaken = Duplicate("email_taken", "email already exists")
+	// ErrInvalidCredentials is returned for an unknown username or a wrong
+	// password
+	ErrInvalidCredentials = Unauthorized("invalid_credentials", "invalid credentials")
 )
 
 type UserService struct {
@@ -41,13 +53,15 @@ func (s *UserService) Register(req *RegisterRequest) error {
 	// Check if username exists
 	_, err := s.userRepo.FindByUsername(req.Username)
 	if err == nil {
-		return errors.New("username already exists")
+		return ErrUsernameTaken
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
 	}
 
 	// Check if email exists
-	_, err = s.userRepo.FindByEmail(req.Email)
-	if err == nil {
-		return errors.New("email already exists")
+	if err := s.requireFreeEmail(req.Email); err != nil {
+		return err
 	}
 
 	user := &model.User{
@@ -62,13 +76,16 @@ func (s *UserService) Register(req *RegisterRequest) error {
 
 func (s *UserService) Login(req *LoginRequest) (*LoginResponse, error) {
 	user, err := s.userRepo.FindByUsername(req.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, err
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 
 	token, err := auth.GenerateToken(user.ID, user.Username, user.Role, s.jwtSecret)
@@ -88,7 +105,7 @@ func (s *UserService) Login(req *LoginRequest) (*LoginResponse, error) {
 func (s *UserService) GetUserByID(id uint) (*model.User, error) {
 	user, err := s.userRepo.FindByID(id)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "user")
 	}
 	user.Password = "" // Clear password from response
 	return user, nil
@@ -97,11 +114,16 @@ func (s *UserService) GetUserByID(id uint) (*model.User, error) {
 func (s *UserService) UpdateUser(id uint, updates *model.User) error {
 	user, err := s.userRepo.FindByID(id)
 	if err != nil {
-		return err
+		return notFound(err, "user")
 	}
 
-	// Only allow updating certain fields
-	user.Email = updates.Email
+	// Only allow updating certain fields, and only those given
+	if updates.Email != "" && updates.Email != user.Email {
+		if err := s.requireFreeEmail(updates.Email); err != nil {
+			return err
+		}
+		user.Email = updates.Email
+	}
 	if updates.Password != "" {
 		user.Password = updates.Password
 	}
@@ -113,18 +135,30 @@ func (s *UserService) DeleteUser(id uint) error {
 	return s.userRepo.Delete(id)
 }
 
-func (s *UserService) ListUsers(page, limit int) ([]model.User, int64, error) {
-	users, total, err := s.userRepo.List(page, limit)
+func (s *UserService) ListUsers(q repository.ListQuery) (*repository.Page[model.User], error) {
+	page, err := s.userRepo.List(q)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
 	// Clear passwords from response
-	for i := range users {
-		users[i].Password = ""
+	for i := range page.Items {
+		page.Items[i].Password = ""
 	}
 
-	return users, total, nil
+	return page, nil
+}
+
+// requireFreeEmail checks that no user has the e-mail address yet
+func (s *UserService) requireFreeEmail(email string) error {
+	_, err := s.userRepo.FindByEmail(email)
+	if err == nil {
+		return ErrEmailTaken
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
 }
 
 func (s *UserService) GetJWTSecret() string {
@@ -0,0 +1,491 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+	"techdocs/pkg/logger"
+	"techdocs/pkg/webhook"
+	"time"
+)
+
+var (
+	// ErrInvalidWebhookURL is returned when a webhook doesn't point at an
+	// absolute http(s) URL
+	ErrInvalidWebhookURL = Invalid("invalid_webhook_url", "webhook URL must be an absolute http or https URL")
+	// ErrInvalidWebhookEvent is returned when a webhook subscribes to an
+	// unknown event
+	ErrInvalidWebhookEvent = Invalid("invalid_webhook_event", "unknown webhook event")
+)
+
+// Webhook delivery settings. A delivery that keeps failing is retried
+// with exponential backoff, starting at webhookBackoff, until it runs out
+// of attempts.
+const (
+	webhookInterval    = 5 * time.Second
+	webhookBatch       = 50
+	webhookTimeout     = 10 * time.Second
+	webhookLease       = time.Minute
+	webhookBackoff     = 30 * time.Second
+	webhookMaxBackoff  = 6 * time.Hour
+	maxWebhookAttempts = 10
+	// maxResponseError is how much of a failed response body is logged
+	maxResponseError = 512
+)
+
+// WebhookEvents are the events a webhook may subscribe to
+var WebhookEvents = []string{
+	model.EventDocumentCreated,
+	model.EventDocumentUpdated,
+	model.EventDocumentDeleted,
+	model.EventDocumentPublished,
+	model.EventDocumentRestored,
+	model.EventServiceCreated,
+	model.EventServiceUpdated,
+	model.EventServiceDeleted,
+	model.EventServiceRestored,
+	model.EventCommentCreated,
+}
+
+// WebhookEvent is the JSON body posted to webhooks
+type WebhookEvent struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	ActorID    uint        `json:"actor_id"`
+	Data       interface{} `json:"data"`
+}
+
+// webhookDocument describes a document in event payloads. Restricted
+// documents are only identified, so receivers can't learn their contents.
+type webhookDocument struct {
+	ID         uint      `json:"id"`
+	Restricted bool      `json:"restricted"`
+	Status     string    `json:"status"`
+	Title      string    `json:"title,omitempty"`
+	Type       string    `json:"type,omitempty"`
+	CategoryID *uint     `json:"category_id,omitempty"`
+	Category   string    `json:"category,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	AuthorID   uint      `json:"author_id,omitempty"`
+	ServiceID  *uint     `json:"service_id,omitempty"`
+	TeamID     *uint     `json:"team_id,omitempty"`
+	Revision   uint      `json:"revision,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// webhookService describes a service in event payloads
+type webhookService struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	TeamID     *uint     `json:"team_id,omitempty"`
+	CategoryID *uint     `json:"category_id,omitempty"`
+	Category   string    `json:"category,omitempty"`
+	Revision   uint      `json:"revision"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// webhookComment describes a comment in event payloads
+type webhookComment struct {
+	ID        uint            `json:"id"`
+	ParentID  *uint           `json:"parent_id,omitempty"`
+	UserID    uint            `json:"user_id"`
+	Content   string          `json:"content,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	Document  webhookDocument `json:"document"`
+}
+
+// WebhookService manages webhooks and delivers events to them through a
+// persistent queue, so deliveries survive restarts and failing receivers
+type WebhookService struct {
+	repo   *repository.WebhookRepository
+	client *http.Client
+}
+
+func NewWebhookService(repo *repository.WebhookRepository) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// CreateWebhook registers a webhook. Without a secret one is generated;
+// either way the secret is returned, as it can't be read back later.
+func (s *WebhookService) CreateWebhook(hook *model.Webhook, actor Actor) (string, error) {
+	if err := validateWebhook(hook); err != nil {
+		return "", err
+	}
+	if hook.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return "", err
+		}
+		hook.Secret = secret
+	}
+
+	hook.CreatedBy = actor.UserID
+	if err := s.repo.Create(hook); err != nil {
+		return "", err
+	}
+	return hook.Secret, nil
+}
+
+// UpdateWebhook changes a webhook's URL, events and active flag. A new
+// secret replaces the current one, an empty one keeps it.
+func (s *WebhookService) UpdateWebhook(hook *model.Webhook) error {
+	if err := validateWebhook(hook); err != nil {
+		return err
+	}
+	existing, err := s.repo.GetByID(hook.ID)
+	if err != nil {
+		return notFound(err, "webhook")
+	}
+
+	existing.URL = hook.URL
+	existing.Events = hook.Events
+	existing.Active = hook.Active
+	if hook.Secret != "" {
+		existing.Secret = hook.Secret
+	}
+	if err := s.repo.Update(existing); err != nil {
+		return err
+	}
+	*hook = *existing
+	return nil
+}
+
+// DeleteWebhook deletes a webhook, abandoning its pending deliveries
+func (s *WebhookService) DeleteWebhook(id uint) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return notFound(err, "webhook")
+	}
+	return s.repo.Delete(id)
+}
+
+// GetWebhook retrieves a webhook by its ID
+func (s *WebhookService) GetWebhook(id uint) (*model.Webhook, error) {
+	hook, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err, "webhook")
+	}
+	return hook, nil
+}
+
+// GetWebhooks retrieves one page of webhooks
+func (s *WebhookService) GetWebhooks(q repository.ListQuery) (*repository.Page[model.Webhook], error) {
+	return s.repo.List(q)
+}
+
+// GetDeliveries retrieves one page of a webhook's delivery log
+func (s *WebhookService) GetDeliveries(webhookID uint, q repository.ListQuery) (*repository.Page[model.WebhookDelivery], error) {
+	if _, err := s.repo.GetByID(webhookID); err != nil {
+		return nil, notFound(err, "webhook")
+	}
+	return s.repo.ListDeliveries(webhookID, q)
+}
+
+// Redeliver queues a delivery to be sent again right away, with a fresh
+// set of attempts
+func (s *WebhookService) Redeliver(webhookID, deliveryID uint) (*model.WebhookDelivery, error) {
+	if _, err := s.repo.GetByID(webhookID); err != nil {
+		return nil, notFound(err, "webhook")
+	}
+	delivery, err := s.repo.GetDelivery(webhookID, deliveryID)
+	if err != nil {
+		return nil, notFound(err, "delivery")
+	}
+
+	delivery.Status = model.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.ResponseStatus = 0
+	delivery.Error = ""
+	delivery.DeliveredAt = nil
+	if err := s.repo.SaveAttempt(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// DispatchDocument queues a document event
+func (s *WebhookService) DispatchDocument(event string, doc *model.Document, actor Actor) {
+	s.dispatch(event, documentPayload(doc), actor)
+}
+
+// DispatchService queues a service event
+func (s *WebhookService) DispatchService(event string, svc *model.Service, actor Actor) {
+	s.dispatch(event, servicePayload(svc), actor)
+}
+
+// DispatchComment queues a comment.created event. Comments on restricted
+// documents are sent without their content.
+func (s *WebhookService) DispatchComment(doc *model.Document, comment *model.Comment, actor Actor) {
+	data := webhookComment{
+		ID:        comment.ID,
+		ParentID:  comment.ParentID,
+		UserID:    comment.UserID,
+		CreatedAt: comment.CreatedAt,
+		Document:  documentPayload(doc),
+	}
+	if !doc.Restricted {
+		data.Content = comment.Content
+	}
+	s.dispatch(model.EventCommentCreated, data, actor)
+}
+
+// dispatch queues an event for every active webhook subscribed to it.
+// Failures are logged rather than returned, so they never undo the change
+// that caused the event.
+func (s *WebhookService) dispatch(event string, data interface{}, actor Actor) {
+	hooks, err := s.repo.GetActive()
+	if err != nil {
+		logger.Error("Failed to load webhooks for %s: %v", event, err)
+		return
+	}
+
+	var deliveries []model.WebhookDelivery
+	var payload []byte
+	for _, hook := range hooks {
+		if !subscribesTo(hook.Events, event) {
+			continue
+		}
+		if payload == nil {
+			payload, err = json.Marshal(WebhookEvent{
+				Event:      event,
+				OccurredAt: time.Now().UTC(),
+				ActorID:    actor.UserID,
+				Data:       data,
+			})
+			if err != nil {
+				logger.Error("Failed to encode %s payload: %v", event, err)
+				return
+			}
+		}
+		deliveries = append(deliveries, model.WebhookDelivery{
+			WebhookID:     hook.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        model.DeliveryPending,
+			NextAttemptAt: time.Now(),
+		})
+	}
+
+	if err := s.repo.Enqueue(deliveries); err != nil {
+		logger.Error("Failed to queue %s deliveries: %v", event, err)
+	}
+}
+
+// Run sends queued deliveries until ctx is cancelled
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.ProcessDue(ctx)
+		}
+	}
+}
+
+// ProcessDue sends the deliveries that are due, batch by batch, until
+// none are left
+func (s *WebhookService) ProcessDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := s.repo.ClaimDue(webhookBatch, webhookLease)
+		if err != nil {
+			logger.Error("Failed to load due webhook deliveries: %v", err)
+			return
+		}
+		for i := range deliveries {
+			s.deliver(ctx, &deliveries[i])
+		}
+		if len(deliveries) < webhookBatch {
+			return
+		}
+	}
+}
+
+// deliver posts a delivery to its webhook and records the outcome. Failed
+// deliveries are rescheduled until they run out of attempts; those of
+// deleted or deactivated webhooks are given up right away.
+func (s *WebhookService) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	var status int
+	var err error
+	abandon := true
+	switch {
+	case delivery.Webhook.ID == 0:
+		err = errors.New("webhook deleted")
+	case !delivery.Webhook.Active:
+		err = errors.New("webhook deactivated")
+	default:
+		abandon = false
+		delivery.Attempts++
+		status, err = s.post(ctx, delivery)
+	}
+	delivery.ResponseStatus = status
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = model.DeliverySucceeded
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+	case abandon || delivery.Attempts >= maxWebhookAttempts:
+		delivery.Status = model.DeliveryFailed
+		delivery.Error = err.Error()
+	default:
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = time.Now().Add(webhookRetryDelay(delivery.Attempts))
+	}
+
+	if err != nil {
+		logger.Error("Webhook delivery ID %d (attempt %d) failed: %v", delivery.ID, delivery.Attempts, err)
+	}
+	if err := s.repo.SaveAttempt(delivery); err != nil {
+		logger.Error("Failed to record webhook delivery ID %d: %v", delivery.ID, err)
+	}
+}
+
+// post sends a delivery's signed payload and returns the response status.
+// Anything but a 2xx response is an error.
+func (s *WebhookService) post(ctx context.Context, delivery *model.WebhookDelivery) (int, error) {
+	hook := delivery.Webhook
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "techdocs-webhooks")
+	req.Header.Set(webhook.EventHeader, delivery.Event)
+	req.Header.Set(webhook.DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(hook.Secret, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseError))
+		return resp.StatusCode, fmt.Errorf("receiver responded %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// webhookRetryDelay returns how long to wait after a delivery failed for
+// the given number of times
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookBackoff
+	for i := 1; i < attempts && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxBackoff {
+		delay = webhookMaxBackoff
+	}
+	return delay
+}
+
+// subscribesTo reports whether a webhook's events match an event, either
+// by name, by a "prefix.*" wildcard or by "*"
+func subscribesTo(events []string, event string) bool {
+	for _, e := range events {
+		if e == event || e == "*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(e, "*"); ok && strings.HasPrefix(event, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWebhook checks a webhook's URL and events
+func validateWebhook(hook *model.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	if len(hook.Events) == 0 {
+		return ErrInvalidWebhookEvent
+	}
+	for _, e := range hook.Events {
+		if !validWebhookEvent(e) {
+			return fmt.Errorf("%w: %s", ErrInvalidWebhookEvent, e)
+		}
+	}
+	return nil
+}
+
+func validWebhookEvent(event string) bool {
+	if event == "*" {
+		return true
+	}
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(event, ".*"); ok && strings.HasPrefix(e, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func documentPayload(doc *model.Document) webhookDocument {
+	data := webhookDocument{ID: doc.ID, Restricted: doc.Restricted, Status: doc.Status, UpdatedAt: doc.UpdatedAt}
+	if doc.Restricted {
+		return data
+	}
+
+	data.Title = doc.Title
+	data.Type = doc.Type
+	data.CategoryID = doc.CategoryID
+	data.Category = categoryName(doc)
+	data.AuthorID = doc.AuthorID
+	data.ServiceID = doc.ServiceID
+	data.TeamID = doc.TeamID
+	data.Revision = doc.Revision
+	for _, tag := range doc.Tags {
+		data.Tags = append(data.Tags, tag.Name)
+	}
+	return data
+}
+
+func servicePayload(svc *model.Service) webhookService {
+	data := webhookService{
+		ID:         svc.ID,
+		Name:       svc.Name,
+		TeamID:     svc.TeamID,
+		CategoryID: svc.CategoryID,
+		Revision:   svc.Revision,
+		UpdatedAt:  svc.UpdatedAt,
+	}
+	if svc.Category != nil {
+		data.Category = svc.Category.Name
+	}
+	return data
+}
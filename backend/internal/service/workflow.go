@@ -0,0 +1,468 @@
+package service
+
+import (
+	"errors"
+	"techdocs/internal/model"
+	"techdocs/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidTransition is returned when a document's status doesn't
+	// allow a change
+	ErrInvalidTransition = Conflict("invalid_transition", "document status does not allow this change")
+	// ErrNotEnoughReviewers is returned when a document is submitted for
+	// review to fewer reviewers than the approvals it needs
+	ErrNotEnoughReviewers = Invalid("not_enough_reviewers", "assign at least as many reviewers as the approvals the document needs")
+	// ErrApprovalsMissing is returned when a document is published before
+	// it got the approvals it needs
+	ErrApprovalsMissing = Conflict("approvals_missing", "document does not have the approvals it needs")
+	// ErrInvalidReviewer is returned when a reviewer is unknown, the
+	// document's author or not allowed to see the document
+	ErrInvalidReviewer = Invalid("invalid_reviewer", "reviewers must be users other than the author who may see the document")
+)
+
+// documentTransitions lists the statuses a document may move to from each
+// status. Drafts are submitted for review, which either sends them back or
+// approves them for publishing. Published documents may be archived and
+// archived ones reopened as drafts.
+var documentTransitions = map[string][]string{
+	model.StatusDraft:     {model.StatusInReview},
+	model.StatusInReview:  {model.StatusDraft, model.StatusApproved},
+	model.StatusApproved:  {model.StatusDraft, model.StatusPublished},
+	model.StatusPublished: {model.StatusArchived},
+	model.StatusArchived:  {model.StatusDraft},
+}
+
+// canTransition reports whether a document may move from one status to
+// another
+func canTransition(from, to string) bool {
+	for _, status := range documentTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ApprovalRules set how many approvals a document needs before it can be
+// published when its category doesn't say
+type ApprovalRules struct {
+	Default int
+}
+
+// Required returns the number of approvals a document of a category needs.
+// Documents without a category need the default.
+func (r ApprovalRules) Required(category *model.Category) int {
+	if category != nil && category.RequiredApprovals > 0 {
+		return category.RequiredApprovals
+	}
+	if r.Default < 1 {
+		return 1
+	}
+	return r.Default
+}
+
+// ReviewState summarizes where a document stands in its review
+type ReviewState struct {
+	RequiredApprovals int                      `json:"required_approvals"`
+	Approvals         int                      `json:"approvals"`
+	Reviewers         []model.DocumentReviewer `json:"reviewers"`
+}
+
+// DocumentDetails is a document along with the trail of pages above it
+// and its review state. Review is left out for readers who only see the
+// published version of a draft.
+type DocumentDetails struct {
+	*model.Document
+	Breadcrumbs []Breadcrumb `json:"breadcrumbs"`
+	Review      *ReviewState `json:"review,omitempty"`
+}
+
+// editable reports whether a document's content may change in its status.
+// Documents in review or approved are frozen so that what is published is
+// what the reviewers signed off.
+func editable(doc *model.Document) bool {
+	return doc.Status == model.StatusDraft || doc.Status == model.StatusPublished
+}
+
+// GetDocumentDetails retrieves a document along with its breadcrumbs and
+// review state
+func (s *DocumentService) GetDocumentDetails(id uint, actor Actor) (*DocumentDetails, error) {
+	doc, err := s.repo.GetByID(id, actor.Viewer())
+	if err != nil {
+		return nil, notFound(err, "document")
+	}
+	breadcrumbs, err := s.breadcrumbs(doc, actor)
+	if err != nil {
+		return nil, err
+	}
+	details := &DocumentDetails{Document: doc, Breadcrumbs: breadcrumbs}
+	// Only a draft shown as its published version is published with a
+	// published version set
+	if doc.Status == model.StatusPublished && doc.PublishedVersion != nil {
+		return details, nil
+	}
+
+	details.Review, err = s.reviewState(doc)
+	if err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// GetReviewers retrieves the reviewers of a document and their decisions
+func (s *DocumentService) GetReviewers(documentID uint, actor Actor) ([]model.DocumentReviewer, error) {
+	if _, err := s.repo.GetByID(documentID, actor.Viewer()); err != nil {
+		return nil, notFound(err, "document")
+	}
+	return s.reviewRepo.GetReviewers(documentID)
+}
+
+// GetPendingReviews retrieves one page of the documents waiting for the
+// actor's review
+func (s *DocumentService) GetPendingReviews(actor Actor, q repository.ListQuery) (*repository.Page[model.Document], error) {
+	return s.reviewRepo.ListPending(actor.UserID, q)
+}
+
+// reviewState counts the approvals of a document against those it needs
+func (s *DocumentService) reviewState(doc *model.Document) (*ReviewState, error) {
+	reviewers, err := s.reviewRepo.GetReviewers(doc.ID)
+	if err != nil {
+		return nil, err
+	}
+	state := &ReviewState{
+		RequiredApprovals: s.approvals.Required(doc.Category),
+		Reviewers:         reviewers,
+	}
+	for _, r := range reviewers {
+		if r.Decision == model.ReviewApproved {
+			state.Approvals++
+		}
+	}
+	return state, nil
+}
+
+// SetReviewers replaces the reviewers of a draft or of a document in
+// review. Reviewers added during a review are asked for their sign-off
+// right away.
+func (s *DocumentService) SetReviewers(documentID uint, userIDs []uint, actor Actor) ([]model.DocumentReviewer, error) {
+	doc, err := s.repo.GetByID(documentID, actor.Viewer())
+	if err != nil {
+		return nil, notFound(err, "document")
+	}
+	if err := s.policy.CanModify(actor, doc, "assign reviewers to"); err != nil {
+		return nil, err
+	}
+	if doc.Status != model.StatusDraft && doc.Status != model.StatusInReview {
+		return nil, ErrInvalidTransition
+	}
+
+	current, err := s.reviewRepo.GetReviewers(documentID)
+	if err != nil {
+		return nil, err
+	}
+	assigned := make(map[uint]bool, len(current))
+	for _, r := range current {
+		assigned[r.UserID] = true
+	}
+
+	userIDs = uniqueIDs(userIDs)
+	var added []uint
+	for _, userID := range userIDs {
+		if assigned[userID] {
+			continue
+		}
+		if err := s.checkReviewer(doc, userID); err != nil {
+			return nil, err
+		}
+		added = append(added, userID)
+	}
+
+	if err := s.reviewRepo.ReplaceReviewers(documentID, userIDs); err != nil {
+		return nil, err
+	}
+
+	if doc.Status == model.StatusInReview {
+		s.notifications.NotifyReview(doc, model.NotificationReviewRequested, added, actor)
+	}
+	return s.reviewRepo.GetReviewers(documentID)
+}
+
+// checkReviewer makes sure a user may review a document
+func (s *DocumentService) checkReviewer(doc *model.Document, userID uint) error {
+	if userID == doc.AuthorID {
+		return ErrInvalidReviewer
+	}
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidReviewer
+		}
+		return err
+	}
+	if !doc.Restricted {
+		return nil
+	}
+
+	permissions, err := s.roleRepo.GetUserPermissions(userID)
+	if err != nil {
+		return err
+	}
+	reviewer := Actor{UserID: userID, Permissions: permissions}
+	open, err := s.repo.IsOpen(doc.ID, reviewer.Viewer())
+	if err != nil {
+		return err
+	}
+	if !open {
+		return ErrInvalidReviewer
+	}
+	return nil
+}
+
+// SubmitDocument opens a review request for a draft. When userIDs is not
+// nil they replace the reviewers first. There must be at least as many
+// reviewers as approvals the document needs. Decisions on an earlier
+// submission are discarded.
+func (s *DocumentService) SubmitDocument(documentID uint, userIDs []uint, actor Actor) (*model.Document, error) {
+	doc, err := s.getForTransition(documentID, model.StatusInReview, "submit", actor)
+	if err != nil {
+		return nil, err
+	}
+
+	var reviewers []model.DocumentReviewer
+	if userIDs != nil {
+		// Check the count first so a rejected submission leaves the
+		// assigned reviewers alone
+		userIDs = uniqueIDs(userIDs)
+		if !s.enoughReviewers(doc, len(userIDs)) {
+			return nil, ErrNotEnoughReviewers
+		}
+		reviewers, err = s.SetReviewers(documentID, userIDs, actor)
+	} else {
+		reviewers, err = s.reviewRepo.GetReviewers(documentID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !s.enoughReviewers(doc, len(reviewers)) {
+		return nil, ErrNotEnoughReviewers
+	}
+	if err := s.reviewRepo.ResetDecisions(documentID); err != nil {
+		return nil, err
+	}
+
+	doc, err = s.transition(doc, model.StatusInReview, doc.PublishedVersion)
+	if err != nil {
+		return nil, err
+	}
+	s.notifications.NotifyReview(doc, model.NotificationReviewRequested, reviewerIDs(reviewers), actor)
+	return doc, nil
+}
+
+// enoughReviewers reports whether count reviewers can give a document
+// the approvals it needs
+func (s *DocumentService) enoughReviewers(doc *model.Document, count int) bool {
+	return count > 0 && count >= s.approvals.Required(doc.Category)
+}
+
+// WithdrawDocument takes a document out of review, or back from approval,
+// so it can be edited again
+func (s *DocumentService) WithdrawDocument(documentID uint, actor Actor) (*model.Document, error) {
+	doc, err := s.getForTransition(documentID, model.StatusDraft, "withdraw", actor)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Status == model.StatusArchived {
+		return nil, ErrInvalidTransition
+	}
+	return s.transition(doc, model.StatusDraft, doc.PublishedVersion)
+}
+
+// ApproveDocument records the actor's sign-off on a document in review.
+// The document is approved once it has the approvals its category needs.
+func (s *DocumentService) ApproveDocument(documentID uint, comment string, actor Actor) (*model.Document, error) {
+	doc, reviewer, err := s.getForReview(documentID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reviewRepo.SetDecision(reviewer, model.ReviewApproved, comment); err != nil {
+		return nil, err
+	}
+	s.notifications.NotifyReview(doc, model.NotificationReviewApproved, []uint{doc.AuthorID}, actor)
+
+	state, err := s.reviewState(doc)
+	if err != nil {
+		return nil, err
+	}
+	if state.Approvals < state.RequiredApprovals {
+		return doc, nil
+	}
+	return s.transition(doc, model.StatusApproved, doc.PublishedVersion)
+}
+
+// CommentOnReview records a reviewer's remark on a document in review
+// without deciding. A decision taken before is kept.
+func (s *DocumentService) CommentOnReview(documentID uint, comment string, actor Actor) (*model.DocumentReviewer, error) {
+	_, reviewer, err := s.getForReview(documentID, actor)
+	if err != nil {
+		return nil, err
+	}
+	decision := reviewer.Decision
+	if decision == model.ReviewPending {
+		decision = model.ReviewCommented
+	}
+	if err := s.reviewRepo.SetDecision(reviewer, decision, comment); err != nil {
+		return nil, err
+	}
+	return reviewer, nil
+}
+
+// RequestChanges records the actor's request for changes on a document in
+// review and sends it back to draft
+func (s *DocumentService) RequestChanges(documentID uint, comment string, actor Actor) (*model.Document, error) {
+	doc, reviewer, err := s.getForReview(documentID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reviewRepo.SetDecision(reviewer, model.ReviewChangesRequested, comment); err != nil {
+		return nil, err
+	}
+
+	doc, err = s.transition(doc, model.StatusDraft, doc.PublishedVersion)
+	if err != nil {
+		return nil, err
+	}
+	s.notifications.NotifyReview(doc, model.NotificationChangesRequested, []uint{doc.AuthorID}, actor)
+	return doc, nil
+}
+
+// PublishDocument makes an approved document visible to all its readers,
+// replacing the version published before. The approvals are checked again
+// in case the required number was raised since the approval.
+func (s *DocumentService) PublishDocument(documentID uint, actor Actor) (*model.Document, error) {
+	doc, err := s.getForTransition(documentID, model.StatusPublished, "publish", actor)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.reviewState(doc)
+	if err != nil {
+		return nil, err
+	}
+	if state.Approvals < state.RequiredApprovals {
+		return nil, ErrApprovalsMissing
+	}
+	previous, err := s.publishedContent(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err = s.transition(doc, model.StatusPublished, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.notifications.NotifyPublishedMentions(doc, previous, actor)
+	s.notifications.NotifySubscribers(doc, model.NotificationDocumentPublished, nil, actor)
+	s.webhooks.DispatchDocument(model.EventDocumentPublished, doc, actor)
+	return doc, nil
+}
+
+// publishedContent returns the content of the version of a document
+// published before, empty when it was never published
+func (s *DocumentService) publishedContent(doc *model.Document) (string, error) {
+	if doc.PublishedVersion == nil {
+		return "", nil
+	}
+	version, err := s.repo.GetVersion(doc.ID, *doc.PublishedVersion)
+	if err != nil {
+		return "", notFound(err, "document version")
+	}
+	return version.Content, nil
+}
+
+// ArchiveDocument retires a published document. It stays readable but can
+// no longer be edited until it is reopened.
+func (s *DocumentService) ArchiveDocument(documentID uint, actor Actor) (*model.Document, error) {
+	doc, err := s.getForTransition(documentID, model.StatusArchived, "archive", actor)
+	if err != nil {
+		return nil, err
+	}
+	return s.transition(doc, model.StatusArchived, nil)
+}
+
+// ReopenDocument turns an archived document back into a draft. It is
+// hidden from readers until it is published again.
+func (s *DocumentService) ReopenDocument(documentID uint, actor Actor) (*model.Document, error) {
+	doc, err := s.getForTransition(documentID, model.StatusDraft, "reopen", actor)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Status != model.StatusArchived {
+		return nil, ErrInvalidTransition
+	}
+	return s.transition(doc, model.StatusDraft, nil)
+}
+
+// getForTransition loads a document the actor may edit and checks that it
+// may move to a status
+func (s *DocumentService) getForTransition(documentID uint, to, action string, actor Actor) (*model.Document, error) {
+	doc, err := s.repo.GetByID(documentID, actor.Viewer())
+	if err != nil {
+		return nil, notFound(err, "document")
+	}
+	if err := s.policy.CanModify(actor, doc, action); err != nil {
+		return nil, err
+	}
+	if !canTransition(doc.Status, to) {
+		return nil, ErrInvalidTransition
+	}
+	return doc, nil
+}
+
+// getForReview loads a document in review along with the actor's review
+// of it. Only assigned reviewers may decide.
+func (s *DocumentService) getForReview(documentID uint, actor Actor) (*model.Document, *model.DocumentReviewer, error) {
+	doc, err := s.repo.GetByID(documentID, actor.Viewer())
+	if err != nil {
+		return nil, nil, notFound(err, "document")
+	}
+	if doc.Status != model.StatusInReview {
+		return nil, nil, ErrInvalidTransition
+	}
+	reviewer, err := s.reviewRepo.GetReviewer(documentID, actor.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrForbidden
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, reviewer, nil
+}
+
+// transition moves a document to a status and returns it as stored
+func (s *DocumentService) transition(doc *model.Document, to string, publishedVersion *int) (*model.Document, error) {
+	if err := s.repo.UpdateStatus(doc.ID, doc.Status, to, publishedVersion); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(doc.ID, repository.Viewer{Admin: true})
+}
+
+func reviewerIDs(reviewers []model.DocumentReviewer) []uint {
+	ids := make([]uint, len(reviewers))
+	for i, r := range reviewers {
+		ids[i] = r.UserID
+	}
+	return ids
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -1,9 +1,13 @@
 package database
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"techdocs/internal/config"
 	"techdocs/internal/model"
+	"techdocs/pkg/slug"
 	"time"
 
 	"gorm.io/driver/mysql"
@@ -19,7 +23,9 @@ func NewMySQLDB(config *config.Config) (*gorm.DB, error) {
 		config.DB.Name,
 	)
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// TranslateError turns driver errors such as duplicate keys into
+	// gorm's own, which the API maps to problem responses
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
@@ -40,12 +46,230 @@ func NewMySQLDB(config *config.Config) (*gorm.DB, error) {
 		&model.Document{},
 		&model.Tag{},
 		&model.Comment{},
+		&model.Notification{},
+		&model.Subscription{},
 		&model.DocumentVersion{},
 		&model.Service{},
+		&model.Space{},
+		&model.Category{},
+		&model.Team{},
+		&model.TeamMember{},
+		&model.DocumentPermission{},
+		&model.DocumentReviewer{},
+		&model.Permission{},
+		&model.Role{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to run migrations: %v", err)
 	}
 
+	if err := migrateCategories(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate categories: %v", err)
+	}
+
+	if err := seedRoles(db); err != nil {
+		return nil, fmt.Errorf("failed to seed roles: %v", err)
+	}
+
 	return db, nil
 }
+
+// categorized are the tables that used to hold free-text categories
+var categorized = []struct {
+	model interface{}
+	table string
+}{
+	{&model.Document{}, "documents"},
+	{&model.DocumentVersion{}, "document_versions"},
+}
+
+// migrateCategories turns the old free-text categories of documents and
+// their versions into categories, matching existing ones by name
+// regardless of case, links the rows to them and drops the old columns
+func migrateCategories(db *gorm.DB) error {
+	migrator := db.Migrator()
+	var pending []string
+	for _, c := range categorized {
+		if migrator.HasColumn(c.model, "category") {
+			pending = append(pending, c.table)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		seen := make(map[string]bool)
+		var names []string
+		for _, table := range pending {
+			var found []string
+			err := tx.Raw("SELECT DISTINCT TRIM(category) FROM " + table + " WHERE TRIM(category) <> ''").Scan(&found).Error
+			if err != nil {
+				return err
+			}
+			for _, name := range found {
+				if key := strings.ToLower(name); !seen[key] {
+					seen[key] = true
+					names = append(names, name)
+				}
+			}
+		}
+		sort.Strings(names)
+
+		var position int64
+		if err := tx.Model(&model.Category{}).Count(&position).Error; err != nil {
+			return err
+		}
+		for _, name := range names {
+			var category model.Category
+			err := tx.Where("name = ?", name).First(&category).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				category = model.Category{Name: name, Position: int(position)}
+				if category.Slug, err = freeSlug(tx, name); err != nil {
+					return err
+				}
+				err = tx.Create(&category).Error
+				position++
+			}
+			if err != nil {
+				return err
+			}
+
+			for _, table := range pending {
+				err := tx.Exec("UPDATE "+table+" SET category_id = ? WHERE TRIM(category) = ?", category.ID, name).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range categorized {
+		if migrator.HasColumn(c.model, "category") {
+			if err := migrator.DropColumn(c.model, "category"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// freeSlug makes a slug from a category name that no category uses yet,
+// numbering it when needed
+func freeSlug(tx *gorm.DB, name string) (string, error) {
+	base := slug.Make(name)
+	if base == "" {
+		base = "category"
+	}
+	candidate := base
+	for n := 2; ; n++ {
+		var count int64
+		if err := tx.Model(&model.Category{}).Where("slug = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// permissions lists every permission the application checks
+var permissions = map[string]string{
+	model.PermDocumentsWrite:   "Create and edit documents",
+	model.PermDocumentsAdmin:   "Read, edit and delete any document regardless of ownership",
+	model.PermServicesManage:   "Create, edit and delete services in the catalog",
+	model.PermTeamsCreate:      "Create teams",
+	model.PermTeamsAdmin:       "Manage any team and its members",
+	model.PermUsersAdmin:       "Manage users, roles and permissions",
+	model.PermWebhooksManage:   "Register webhooks and inspect their deliveries",
+	model.PermTrashPurge:       "Permanently delete documents and services from the trash",
+	model.PermSpacesManage:     "Create, edit and delete documentation spaces",
+	model.PermCategoriesManage: "Create, edit and delete the categories of documents and services",
+	model.PermTagsManage:       "Rename tags and merge them into one another",
+}
+
+// defaultRoles are created with these permissions when missing. Existing
+// roles are left alone so changes made by admins stick, except that they
+// receive permissions introduced since they were created.
+var defaultRoles = map[string][]string{
+	"admin": {
+		model.PermDocumentsWrite,
+		model.PermDocumentsAdmin,
+		model.PermServicesManage,
+		model.PermTeamsCreate,
+		model.PermTeamsAdmin,
+		model.PermUsersAdmin,
+		model.PermWebhooksManage,
+		model.PermTrashPurge,
+		model.PermSpacesManage,
+		model.PermCategoriesManage,
+		model.PermTagsManage,
+	},
+	"platform_engineer": {
+		model.PermDocumentsWrite,
+		model.PermServicesManage,
+		model.PermTeamsCreate,
+		model.PermSpacesManage,
+		model.PermTagsManage,
+	},
+	"user": {
+		model.PermDocumentsWrite,
+	},
+}
+
+// seedRoles makes sure all permissions and the default roles exist
+func seedRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		byName := make(map[string]model.Permission, len(permissions))
+		added := make(map[string]bool)
+		for name, description := range permissions {
+			var permission model.Permission
+			err := tx.Where("name = ?", name).First(&permission).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				permission = model.Permission{Name: name, Description: description}
+				err = tx.Create(&permission).Error
+				added[name] = true
+			}
+			if err != nil {
+				return err
+			}
+			byName[name] = permission
+		}
+
+		for name, granted := range defaultRoles {
+			var existing model.Role
+			err := tx.Where("name = ?", name).First(&existing).Error
+			if err == nil {
+				for _, permission := range granted {
+					if !added[permission] {
+						continue
+					}
+					grant := byName[permission]
+					if err := tx.Model(&existing).Association("Permissions").Append(&grant); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			role := model.Role{Name: name}
+			for _, permission := range granted {
+				role.Permissions = append(role.Permissions, byName[permission])
+			}
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
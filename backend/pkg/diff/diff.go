@@ -0,0 +1,236 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line kinds used in hunks
+const (
+	Context = "context"
+	Insert  = "insert"
+	Delete  = "delete"
+)
+
+// DefaultContext is the number of unchanged lines kept around each change
+const DefaultContext = 3
+
+// Line is a single line of a hunk. OldLine and NewLine are 1-based and zero
+// when the line doesn't exist on that side.
+type Line struct {
+	Kind    string `json:"kind"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// Hunk is a group of changed lines with surrounding context
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Lines    []Line `json:"lines"`
+}
+
+// op is one step of an edit script, with 0-based positions in both inputs
+type op struct {
+	kind string
+	old  int
+	new  int
+	text string
+}
+
+// Lines computes a line-level diff of two texts and groups it into hunks
+// with the given number of context lines
+func Lines(a, b string, context int) []Hunk {
+	ops := editScript(splitLines(a), splitLines(b))
+	return hunks(ops, context)
+}
+
+// Unified renders hunks in unified diff format
+func Unified(fromLabel, toLabel string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n", fromLabel)
+	fmt.Fprintf(&sb, "+++ %s\n", toLabel)
+	for _, h := range hunks {
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", rangeHeader(h.OldStart, h.OldLines), rangeHeader(h.NewStart, h.NewLines))
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case Insert:
+				sb.WriteByte('+')
+			case Delete:
+				sb.WriteByte('-')
+			default:
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(l.Text)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+func rangeHeader(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// editScript returns the shortest edit script turning a into b, using
+// Myers' O(ND) algorithm
+func editScript(a, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	v := make([]int, 2*max+2)
+	// trace[d] holds v for diagonals -d..d as it was before step d
+	var trace [][]int
+	var found bool
+	for d := 0; d <= max && !found; d++ {
+		trace = append(trace, append([]int(nil), v[max-d:max+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1]
+			} else {
+				x = v[max+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	var ops []op
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d]
+		at := func(k int) int { return prev[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{kind: Context, old: x, new: y, text: a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, op{kind: Insert, old: x, new: y, text: b[y]})
+		} else {
+			x--
+			ops = append(ops, op{kind: Delete, old: x, new: y, text: a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, op{kind: Context, old: x, new: y, text: a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// hunks groups an edit script into hunks, merging changes that are closer
+// than twice the context size
+func hunks(ops []op, context int) []Hunk {
+	var result []Hunk
+	i := 0
+	for i < len(ops) {
+		if ops[i].kind == Context {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk while the next change is within reach
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != Context {
+				end = j
+			} else if j-end > 2*context {
+				break
+			}
+		}
+		stop := end + context + 1
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		result = append(result, newHunk(ops[start:stop]))
+		i = stop
+	}
+	return result
+}
+
+func newHunk(ops []op) Hunk {
+	h := Hunk{
+		OldStart: ops[0].old + 1,
+		NewStart: ops[0].new + 1,
+	}
+	for _, o := range ops {
+		line := Line{Kind: o.kind, Text: o.text}
+		switch o.kind {
+		case Context:
+			h.OldLines++
+			h.NewLines++
+			line.OldLine = o.old + 1
+			line.NewLine = o.new + 1
+		case Insert:
+			h.NewLines++
+			line.NewLine = o.new + 1
+		case Delete:
+			h.OldLines++
+			line.OldLine = o.old + 1
+		}
+		h.Lines = append(h.Lines, line)
+	}
+
+	// An empty side points at the line before the change, as in GNU diff
+	if h.OldLines == 0 {
+		h.OldStart--
+	}
+	if h.NewLines == 0 {
+		h.NewStart--
+	}
+	return h
+}
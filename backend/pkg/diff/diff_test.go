@@ -0,0 +1,101 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    []Hunk
+	}{
+		{"both empty", "", "", 3, nil},
+		{"identical", "a\nb\n", "a\nb\n", 3, nil},
+		{"line endings and trailing newline don't count", "a\r\nb\r\n", "a\nb", 3, nil},
+		{
+			"empty to non-empty", "", "a\nb\n", 3,
+			[]Hunk{{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 2, Lines: []Line{
+				{Kind: Insert, Text: "a", NewLine: 1},
+				{Kind: Insert, Text: "b", NewLine: 2},
+			}}},
+		},
+		{
+			"non-empty to empty", "a\nb\n", "", 3,
+			[]Hunk{{OldStart: 1, OldLines: 2, NewStart: 0, NewLines: 0, Lines: []Line{
+				{Kind: Delete, Text: "a", OldLine: 1},
+				{Kind: Delete, Text: "b", OldLine: 2},
+			}}},
+		},
+		{
+			"changed line with context", "a\nb\nc\nd\n", "a\nx\nc\nd\n", 1,
+			[]Hunk{{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3, Lines: []Line{
+				{Kind: Context, Text: "a", OldLine: 1, NewLine: 1},
+				{Kind: Delete, Text: "b", OldLine: 2},
+				{Kind: Insert, Text: "x", NewLine: 2},
+				{Kind: Context, Text: "c", OldLine: 3, NewLine: 3},
+			}}},
+		},
+		{
+			"appended line", "a\nb\n", "a\nb\nc\n", 1,
+			[]Hunk{{OldStart: 2, OldLines: 1, NewStart: 2, NewLines: 2, Lines: []Line{
+				{Kind: Context, Text: "b", OldLine: 2, NewLine: 2},
+				{Kind: Insert, Text: "c", NewLine: 3},
+			}}},
+		},
+		{
+			"close changes share a hunk", "a\nb\nc", "x\nb\ny", 1,
+			[]Hunk{{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3, Lines: []Line{
+				{Kind: Delete, Text: "a", OldLine: 1},
+				{Kind: Insert, Text: "x", NewLine: 1},
+				{Kind: Context, Text: "b", OldLine: 2, NewLine: 2},
+				{Kind: Delete, Text: "c", OldLine: 3},
+				{Kind: Insert, Text: "y", NewLine: 3},
+			}}},
+		},
+		{
+			"distant changes get their own hunks", "1\n2\n3\n4\n5\n6\n7\n8", "x\n2\n3\n4\n5\n6\n7\ny", 1,
+			[]Hunk{
+				{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2, Lines: []Line{
+					{Kind: Delete, Text: "1", OldLine: 1},
+					{Kind: Insert, Text: "x", NewLine: 1},
+					{Kind: Context, Text: "2", OldLine: 2, NewLine: 2},
+				}},
+				{OldStart: 7, OldLines: 2, NewStart: 7, NewLines: 2, Lines: []Line{
+					{Kind: Context, Text: "7", OldLine: 7, NewLine: 7},
+					{Kind: Delete, Text: "8", OldLine: 8},
+					{Kind: Insert, Text: "y", NewLine: 8},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines(tt.a, tt.b, tt.context); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"no changes", "a\n", "a\n", ""},
+		{"empty to non-empty", "", "a\nb\n", "--- v1\n+++ v2\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"non-empty to empty", "a\n", "", "--- v1\n+++ v2\n@@ -1 +0,0 @@\n-a\n"},
+		{"changed line", "a\nb\n", "a\nc\n", "--- v1\n+++ v2\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("v1", "v2", Lines(tt.a, tt.b, DefaultContext)); got != tt.want {
+				t.Errorf("Unified = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package mention
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pattern matches @username where the @ doesn't follow a word character,
+// so e-mail addresses aren't taken for mentions
+var pattern = regexp.MustCompile(`(^|[^\w@])@(\w[\w.-]*)`)
+
+// inlineCode matches Markdown code spans
+var inlineCode = regexp.MustCompile("`[^`\n]*`")
+
+// Parse returns the usernames mentioned in a Markdown text, in order of
+// first appearance. Mentions inside code blocks and code spans are ignored,
+// as are trailing dots and dashes, which usually end a sentence.
+func Parse(text string) []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		line = inlineCode.ReplaceAllString(line, " ")
+		for _, m := range pattern.FindAllStringSubmatch(line, -1) {
+			name := strings.TrimRight(m[2], ".-")
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Added returns the usernames mentioned in text that previous didn't
+// mention yet
+func Added(previous, text string) []string {
+	before := make(map[string]bool)
+	for _, name := range Parse(previous) {
+		before[name] = true
+	}
+
+	var added []string
+	for _, name := range Parse(text) {
+		if !before[name] {
+			added = append(added, name)
+		}
+	}
+	return added
+}
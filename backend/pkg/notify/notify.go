@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// Message is an e-mail with a plain-text and an HTML body
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Notifier delivers messages to people outside the app
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Retry wraps a Notifier and retries failed deliveries, doubling the wait
+// after every attempt
+type Retry struct {
+	Notifier Notifier
+	Attempts int
+	Backoff  time.Duration
+}
+
+// Notify implements Notifier
+func (r Retry) Notify(ctx context.Context, msg Message) error {
+	wait := r.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = r.Notifier.Notify(ctx, msg); err == nil || attempt >= r.Attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
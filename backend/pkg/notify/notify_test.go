@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stubNotifier fails its first failures calls and counts all of them
+type stubNotifier struct {
+	failures int
+	calls    int
+}
+
+func (s *stubNotifier) Notify(ctx context.Context, msg Message) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("server unavailable")
+	}
+	return nil
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		attempts  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"succeeds at once", 0, 3, 1, false},
+		{"succeeds after failures", 2, 3, 3, false},
+		{"stops after attempts", 5, 3, 3, true},
+		{"single attempt", 1, 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubNotifier{failures: tt.failures}
+			retry := Retry{Notifier: stub, Attempts: tt.attempts, Backoff: time.Millisecond}
+
+			err := retry.Notify(context.Background(), Message{To: []string{"ada@example.com"}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Notify error = %v, want error: %v", err, tt.wantErr)
+			}
+			if stub.calls != tt.wantCalls {
+				t.Errorf("Notify called %d times, want %d", stub.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenContextIsCancelled(t *testing.T) {
+	stub := &stubNotifier{failures: 5}
+	retry := Retry{Notifier: stub, Attempts: 5, Backoff: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	done := make(chan error, 1)
+	go func() { done <- retry.Notify(ctx, Message{To: []string{"ada@example.com"}}) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Notify error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify kept waiting after the context was cancelled")
+	}
+	if stub.calls != 1 {
+		t.Errorf("Notify called %d times, want 1", stub.calls)
+	}
+}
@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is an SMTP server that accepts one message and records the
+// envelope and data it was sent
+type fakeSMTP struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     []byte
+	done     chan error
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTP{listener: listener, done: make(chan error, 1)}
+	t.Cleanup(func() { listener.Close() })
+	go func() { s.done <- s.serve() }()
+	return s
+}
+
+func (s *fakeSMTP) port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+func (s *fakeSMTP) serve() error {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	r := textproto.NewReader(bufio.NewReader(conn))
+	w := textproto.NewWriter(bufio.NewWriter(conn))
+	reply := func(lines ...string) error {
+		for _, line := range lines {
+			if err := w.PrintfLine("%s", line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := reply("220 localhost fake ESMTP"); err != nil {
+		return err
+	}
+	for {
+		line, err := r.ReadLine()
+		if err != nil {
+			return err
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			err = reply("250-localhost", "250 8BITMIME")
+		case "MAIL":
+			s.from = envelopeAddress(arg)
+			err = reply("250 OK")
+		case "RCPT":
+			s.to = append(s.to, envelopeAddress(arg))
+			err = reply("250 OK")
+		case "DATA":
+			if err = reply("354 Go ahead"); err != nil {
+				return err
+			}
+			if s.data, err = r.ReadDotBytes(); err != nil {
+				return err
+			}
+			err = reply("250 OK")
+		case "QUIT":
+			return reply("221 Bye")
+		default:
+			err = reply("502 Not implemented")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// envelopeAddress returns the address between the angle brackets of a MAIL or
+// RCPT argument, dropping any parameters after it
+func envelopeAddress(arg string) string {
+	_, rest, _ := strings.Cut(arg, "<")
+	address, _, _ := strings.Cut(rest, ">")
+	return address
+}
+
+func TestSMTPNotifierNotify(t *testing.T) {
+	server := newFakeSMTP(t)
+	notifier := NewSMTPNotifier(SMTPConfig{Host: "127.0.0.1", Port: server.port(), From: "docs@example.com"})
+
+	msg := Message{
+		To:      []string{"ada@example.com", "grace@example.com"},
+		Subject: "Grüße: Runbook aktualisiert",
+		Text:    "The runbook changed.\nSee the new steps.",
+		HTML:    `<p>The <a href="https://docs.example.com/document/1">runbook</a> changed.</p>`,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := notifier.Notify(ctx, msg); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if err := <-server.done; err != nil {
+		t.Fatalf("server: %v", err)
+	}
+
+	if server.from != "docs@example.com" {
+		t.Errorf("MAIL FROM = %q, want docs@example.com", server.from)
+	}
+	if strings.Join(server.to, ",") != "ada@example.com,grace@example.com" {
+		t.Errorf("RCPT TO = %v, want both recipients", server.to)
+	}
+
+	email, err := mail.ReadMessage(strings.NewReader(string(server.data)))
+	if err != nil {
+		t.Fatalf("reading DATA: %v", err)
+	}
+	subject := email.Header.Get("Subject")
+	if !strings.HasPrefix(subject, "=?utf-8?q?") {
+		t.Errorf("Subject %q is not Q-encoded", subject)
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(subject); err != nil || decoded != msg.Subject {
+		t.Errorf("decoded Subject = %q (%v), want %q", decoded, err, msg.Subject)
+	}
+	if to := email.Header.Get("To"); to != "ada@example.com, grace@example.com" {
+		t.Errorf("To = %q", to)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(email.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q (%v), want multipart/alternative", mediaType, err)
+	}
+	parts := multipart.NewReader(email.Body, params["boundary"])
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, w := range want {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatalf("reading %s part: %v", w.contentType, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != w.contentType {
+			t.Errorf("part Content-Type = %q, want %q", got, w.contentType)
+		}
+		if got := part.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+			t.Errorf("part Content-Transfer-Encoding = %q, want quoted-printable", got)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatalf("decoding %s part: %v", w.contentType, err)
+		}
+		if string(body) != w.body {
+			t.Errorf("%s part = %q, want %q", w.contentType, body, w.body)
+		}
+	}
+	if _, err := parts.NextRawPart(); err != io.EOF {
+		t.Errorf("expected two parts, next part error = %v", err)
+	}
+}
+
+func TestSMTPNotifierRejectsMessageWithoutRecipients(t *testing.T) {
+	notifier := NewSMTPNotifier(SMTPConfig{Host: "127.0.0.1", Port: "1", From: "docs@example.com"})
+	if err := notifier.Notify(context.Background(), Message{Subject: "Hi"}); err == nil {
+		t.Fatal("Notify without recipients succeeded")
+	}
+}
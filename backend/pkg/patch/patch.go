@@ -0,0 +1,349 @@
+// Package patch applies JSON Merge Patches (RFC 7396) and JSON Patches
+// (RFC 6902) to JSON documents
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned for a malformed patch or an operation
+	// that doesn't fit the document
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrTestFailed is returned when a test operation doesn't hold
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// Operation is a single step of a JSON Patch. Value is nil when the
+// operation has no value member.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Merge applies a JSON Merge Patch to a document. Members set to null in
+// the patch are removed, objects are merged and anything else replaces the
+// document's value.
+func Merge(doc, patch []byte) ([]byte, error) {
+	var target, changes any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return json.Marshal(merge(target, changes))
+}
+
+func merge(target, changes any) any {
+	members, ok := changes.(map[string]any)
+	if !ok {
+		return changes
+	}
+	object, ok := target.(map[string]any)
+	if !ok {
+		object = map[string]any{}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(object, name)
+			continue
+		}
+		object[name] = merge(object[name], value)
+	}
+	return object
+}
+
+// Apply applies a JSON Patch to a document. The operations run in order
+// and the document is left unchanged unless all of them succeed.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var err error
+		if target, err = apply(target, op); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func apply(doc any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		value, err := opValue(op)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			if _, err := get(doc, path); err != nil {
+				return nil, err
+			}
+			if doc, _, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("%w: value at %q differs", ErrTestFailed, op.Path)
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return add(doc, path, clone(value))
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: can't move %q into itself", ErrInvalidPatch, op.From)
+		}
+		if doc, _, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+func opValue(op Operation) (any, error) {
+	if op.Value == nil {
+		return nil, fmt.Errorf("%w: %s needs a value", ErrInvalidPatch, op.Op)
+	}
+	var value any
+	if err := json.Unmarshal(op.Value, &value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return value, nil
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped
+// reference tokens. The empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// get returns the value a path refers to
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrInvalidPatch, token)
+			}
+			doc = value
+		case []any:
+			i, err := index(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: %q is below a value that is neither object nor array", ErrInvalidPatch, token)
+		}
+	}
+	return doc, nil
+}
+
+// add sets a member of an object or inserts an element into an array,
+// where "-" appends, and returns the changed document
+func add(doc any, path []string, value any) (any, error) {
+	return change(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			i := len(node)
+			if token != "-" {
+				var err error
+				if i, err = index(token, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("%w: can't add %q to a value that is neither object nor array", ErrInvalidPatch, token)
+		}
+	}, value)
+}
+
+// remove deletes the value a path refers to and returns the changed
+// document along with the removed value
+func remove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: can't remove the whole document", ErrInvalidPatch)
+	}
+	var removed any
+	doc, err := change(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrInvalidPatch, token)
+			}
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []any:
+			i, err := index(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("%w: can't remove %q from a value that is neither object nor array", ErrInvalidPatch, token)
+		}
+	}, nil)
+	return doc, removed, err
+}
+
+// change runs edit on the container holding the last token of path and
+// puts the container it returns back in place. An empty path replaces the
+// whole document with root.
+func change(doc any, path []string, edit func(parent any, token string) (any, error), root any) (any, error) {
+	if len(path) == 0 {
+		return root, nil
+	}
+	if len(path) == 1 {
+		return edit(doc, path[0])
+	}
+
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q does not exist", ErrInvalidPatch, token)
+		}
+		child, err := change(child, path[1:], edit, root)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+	case []any:
+		i, err := index(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := change(node[i], path[1:], edit, root)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+	default:
+		return nil, fmt.Errorf("%w: %q is below a value that is neither object nor array", ErrInvalidPatch, token)
+	}
+}
+
+// index parses an array index no larger than max
+func index(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: array index %q is out of range", ErrInvalidPatch, token)
+	}
+	return i, nil
+}
+
+// clone returns a deep copy of a decoded JSON value
+func clone(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for name, member := range v {
+			c[name] = clone(member)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, element := range v {
+			c[i] = clone(element)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// Changed returns the sorted names of the members that differ between two
+// JSON objects, including members only one of them has
+func Changed(before, after []byte) ([]string, error) {
+	var old, updated map[string]any
+	if err := json.Unmarshal(before, &old); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &updated); err != nil || updated == nil {
+		return nil, fmt.Errorf("%w: the patched document must be an object", ErrInvalidPatch)
+	}
+
+	var changed []string
+	for name, value := range updated {
+		if previous, ok := old[name]; !ok || !reflect.DeepEqual(previous, value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range old {
+		if _, ok := updated[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
@@ -0,0 +1,118 @@
+package patch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{"replaces a member", `{"a":1,"b":2}`, `{"a":3}`, `{"a":3,"b":2}`, nil},
+		{"adds a member", `{"a":1}`, `{"b":"x"}`, `{"a":1,"b":"x"}`, nil},
+		{"removes members set to null", `{"a":1,"b":2}`, `{"b":null}`, `{"a":1}`, nil},
+		{"ignores null for missing members", `{"a":1}`, `{"b":null}`, `{"a":1}`, nil},
+		{"merges nested objects", `{"a":{"x":1,"y":2}}`, `{"a":{"y":null,"z":3}}`, `{"a":{"x":1,"z":3}}`, nil},
+		{"replaces arrays whole", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`, nil},
+		{"replaces a value with an object", `{"a":1}`, `{"a":{"b":null,"c":2}}`, `{"a":{"c":2}}`, nil},
+		{"replaces the document with a non-object", `{"a":1}`, `[1]`, `[1]`, nil},
+		{"rejects malformed patches", `{"a":1}`, `{`, "", ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge([]byte(tt.doc), []byte(tt.patch))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Merge error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Merge = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{"adds a member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`, nil},
+		{"appends with -", `{"a":[1,2]}`, `[{"op":"add","path":"/a/-","value":3}]`, `{"a":[1,2,3]}`, nil},
+		{"appends with - to an empty array", `{"a":[]}`, `[{"op":"add","path":"/a/-","value":1}]`, `{"a":[1]}`, nil},
+		{"inserts at an index", `{"a":[1,2]}`, `[{"op":"add","path":"/a/0","value":0}]`, `{"a":[0,1,2]}`, nil},
+		{"inserts at the end", `{"a":[1,2]}`, `[{"op":"add","path":"/a/2","value":3}]`, `{"a":[1,2,3]}`, nil},
+		{"rejects an index past the end", `{"a":[1,2]}`, `[{"op":"add","path":"/a/3","value":3}]`, "", ErrInvalidPatch},
+		{"rejects an index with a leading zero", `{"a":[1,2]}`, `[{"op":"add","path":"/a/01","value":3}]`, "", ErrInvalidPatch},
+		{"rejects - outside of add", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/-"}]`, "", ErrInvalidPatch},
+		{"removes a member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/b"}]`, `{"a":1}`, nil},
+		{"removes an element", `{"a":[1,2,3]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1,3]}`, nil},
+		{"rejects removing a missing member", `{"a":1}`, `[{"op":"remove","path":"/b"}]`, "", ErrInvalidPatch},
+		{"replaces a member", `{"a":1}`, `[{"op":"replace","path":"/a","value":"x"}]`, `{"a":"x"}`, nil},
+		{"replaces an element", `{"a":[1,2]}`, `[{"op":"replace","path":"/a/1","value":5}]`, `{"a":[1,5]}`, nil},
+		{"replaces the document", `{"a":1}`, `[{"op":"replace","path":"","value":{"b":2}}]`, `{"b":2}`, nil},
+		{"rejects replacing a missing member", `{"a":1}`, `[{"op":"replace","path":"/b","value":2}]`, "", ErrInvalidPatch},
+		{"sets null values", `{"a":1}`, `[{"op":"replace","path":"/a","value":null}]`, `{"a":null}`, nil},
+		{"moves a member", `{"a":1}`, `[{"op":"move","from":"/a","path":"/b"}]`, `{"b":1}`, nil},
+		{"moves an element", `{"a":[1,2,3]}`, `[{"op":"move","from":"/a/0","path":"/a/-"}]`, `{"a":[2,3,1]}`, nil},
+		{"rejects moving a member into itself", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, "", ErrInvalidPatch},
+		{"copies a member", `{"a":{"x":1}}`, `[{"op":"copy","from":"/a","path":"/b"}]`, `{"a":{"x":1},"b":{"x":1}}`, nil},
+		{"copies by value", `{"a":{"x":1}}`, `[{"op":"copy","from":"/a","path":"/b"},{"op":"add","path":"/b/y","value":2}]`, `{"a":{"x":1},"b":{"x":1,"y":2}}`, nil},
+		{"unescapes pointers", `{"a/b":1,"c~d":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"remove","path":"/c~0d"}]`, `{}`, nil},
+		{"runs after a passing test", `{"a":[1,{"b":2}]}`, `[{"op":"test","path":"/a","value":[1,{"b":2}]},{"op":"add","path":"/c","value":3}]`, `{"a":[1,{"b":2}],"c":3}`, nil},
+		{"fails on a differing test", `{"a":1}`, `[{"op":"test","path":"/a","value":2},{"op":"add","path":"/c","value":3}]`, "", ErrTestFailed},
+		{"fails on a test of a different type", `{"a":1}`, `[{"op":"test","path":"/a","value":"1"}]`, "", ErrTestFailed},
+		{"rejects a test of a missing member", `{"a":1}`, `[{"op":"test","path":"/b","value":1}]`, "", ErrInvalidPatch},
+		{"rejects a missing value", `{"a":1}`, `[{"op":"add","path":"/b"}]`, "", ErrInvalidPatch},
+		{"rejects unknown operations", `{"a":1}`, `[{"op":"rename","path":"/a"}]`, "", ErrInvalidPatch},
+		{"rejects paths without a leading slash", `{"a":1}`, `[{"op":"remove","path":"a"}]`, "", ErrInvalidPatch},
+		{"rejects a patch that isn't a list", `{"a":1}`, `{"op":"remove","path":"/a"}`, "", ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Apply = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		before  string
+		after   string
+		want    []string
+		wantErr error
+	}{
+		{"nothing changed", `{"a":1,"b":[1]}`, `{"b":[1],"a":1}`, nil, nil},
+		{"changed members", `{"a":1,"b":{"x":1}}`, `{"a":2,"b":{"x":2}}`, []string{"a", "b"}, nil},
+		{"added and removed members", `{"a":1,"b":2}`, `{"b":2,"c":3}`, []string{"a", "c"}, nil},
+		{"member set to null", `{"a":1}`, `{"a":null}`, []string{"a"}, nil},
+		{"rejects a patched document that isn't an object", `{"a":1}`, `[1]`, nil, ErrInvalidPatch},
+		{"rejects null as the patched document", `{"a":1}`, `null`, nil, ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Changed([]byte(tt.before), []byte(tt.after))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Changed error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Changed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Snippet lengths around the first match, in runes
+const (
+	snippetBefore = 60
+	snippetAfter  = 140
+)
+
+// Terms splits a query into lower-cased search terms
+func Terms(query string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool, len(fields))
+	terms := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if !seen[f] {
+			seen[f] = true
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// Snippet cuts a short excerpt of text around the first occurrence of any
+// term and wraps every occurrence in <mark> tags. The text is HTML escaped,
+// so the result can be rendered as HTML. Without a match the start of the
+// text is returned.
+func Snippet(text string, terms []string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	// Lower-casing can change the length of some runes, fall back to no
+	// highlighting rather than misaligned offsets
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+
+	first := -1
+	for _, term := range terms {
+		if i := indexRunes(lower, []rune(term), 0); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+
+	start, end := 0, len(runes)
+	if first >= 0 {
+		start = first - snippetBefore
+		if start < 0 {
+			start = 0
+		}
+		end = first + snippetAfter
+	} else {
+		end = snippetBefore + snippetAfter
+	}
+	if end > len(runes) {
+		end = len(runes)
+	}
+	start, end = wordBounds(runes, start, end)
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	sb.WriteString(highlight(runes[start:end], lower[start:end], terms))
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// highlight escapes text and wraps every term occurrence in <mark> tags
+func highlight(text, lower []rune, terms []string) string {
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		t := []rune(term)
+		for i := indexRunes(lower, t, 0); i >= 0; i = indexRunes(lower, t, i+len(t)) {
+			for j := i; j < i+len(t); j++ {
+				marked[j] = true
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		chunk := html.EscapeString(string(text[i:j]))
+		if marked[i] {
+			sb.WriteString("<mark>" + chunk + "</mark>")
+		} else {
+			sb.WriteString(chunk)
+		}
+		i = j
+	}
+	return sb.String()
+}
+
+// wordBounds widens start and narrows end so the snippet doesn't cut words
+func wordBounds(runes []rune, start, end int) (int, int) {
+	for start > 0 && !unicode.IsSpace(runes[start-1]) {
+		start--
+	}
+	if end < len(runes) {
+		e := end
+		for e > start && !unicode.IsSpace(runes[e-1]) {
+			e--
+		}
+		if e > start {
+			end = e
+		}
+	}
+	return start, end
+}
+
+func indexRunes(s, sub []rune, from int) int {
+	if len(sub) == 0 {
+		return -1
+	}
+	for i := from; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,35 @@
+package slug
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// pattern matches a valid slug: lowercase letters and digits in groups
+// separated by single dashes
+var pattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Make turns a name into a slug, e.g. "API & SDKs" into "api-sdks". Letters
+// other than ASCII ones are dropped, so the result may be empty.
+func Make(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// Valid reports whether s is a well-formed slug
+func Valid(s string) bool {
+	return pattern.MatchString(s)
+}
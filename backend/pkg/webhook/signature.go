@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Headers sent with every delivery
+const (
+	EventHeader     = "X-Techdocs-Event"
+	DeliveryHeader  = "X-Techdocs-Delivery"
+	SignatureHeader = "X-Techdocs-Signature-256"
+)
+
+// Sign returns the signature of a payload as sent in SignatureHeader: the
+// hex encoded HMAC-SHA256 of the body keyed with the webhook's secret,
+// prefixed with "sha256="
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for body, comparing in
+// constant time. Receivers can use it to authenticate deliveries.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
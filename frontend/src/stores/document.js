@@ -2,20 +2,55 @@ import { defineStore } from "pinia";
 import axios from "axios";
 import { config } from "../config";
 
+// The API takes tags by name
+const tagNames = (tags) =>
+  tags.map((tag) =>
+    typeof tag === "object" && tag.name ? tag.name : String(tag)
+  );
+
+// Number of documents loaded per page
+const PAGE_SIZE = 24;
+
 export const useDocumentStore = defineStore("document", {
   state: () => ({
     documents: [],
+    categories: [],
+    total: 0,
+    nextCursor: "",
+    params: {},
     loading: false,
     error: null,
   }),
 
   actions: {
-    async fetchDocuments() {
+    // Loads the first page of documents matching params. Further pages are
+    // loaded with fetchMoreDocuments.
+    async fetchDocuments(params = {}) {
+      this.params = params;
+      this.nextCursor = "";
+      this.documents = await this.fetchDocumentPage();
+    },
+
+    // Appends the next page of documents, if there is one
+    async fetchMoreDocuments() {
+      if (!this.nextCursor) {
+        return;
+      }
+      const documents = await this.fetchDocumentPage(this.nextCursor);
+      this.documents.push(...documents);
+    },
+
+    async fetchDocumentPage(cursor = "") {
       this.loading = true;
       try {
         const response = await axios.get(
           `${config.api.baseUrl}/api/documents`,
           {
+            params: {
+              limit: PAGE_SIZE,
+              ...this.params,
+              ...(cursor && { cursor }),
+            },
             headers: {
               Authorization: `Bearer ${localStorage.getItem(
                 config.auth.tokenKey
@@ -23,11 +58,11 @@ export const useDocumentStore = defineStore("document", {
             },
           }
         );
-        if (response.data.length > 0) {
-        }
-        this.documents = response.data;
+        this.total = response.data.total;
+        this.nextCursor = response.data.next_cursor || "";
+        return response.data.items;
       } catch (error) {
-        this.error = error.response?.data?.error || "Failed to fetch documents";
+        this.error = error.response?.data?.detail || "Failed to fetch documents";
         throw error;
       } finally {
         this.loading = false;
@@ -59,7 +94,7 @@ export const useDocumentStore = defineStore("document", {
         });
         return response.data;
       } catch (error) {
-        this.error = error.response?.data?.error || "Failed to fetch document";
+        this.error = error.response?.data?.detail || "Failed to fetch document";
         throw error;
       } finally {
         this.loading = false;
@@ -71,16 +106,7 @@ export const useDocumentStore = defineStore("document", {
       try {
         const formattedDocument = {
           ...document,
-          tags: document.tags.map((tag) => {
-            if (typeof tag === "object" && tag.name) {
-              return tag;
-            } else if (typeof tag === "string") {
-              return { name: tag };
-            } else {
-              console.warn("Unexpected tag format:", tag);
-              return { name: String(tag) };
-            }
-          }),
+          tags: tagNames(document.tags),
           // Ensure serviceId is a number or null
           serviceId: document.serviceId
             ? parseInt(document.serviceId, 10)
@@ -112,7 +138,7 @@ export const useDocumentStore = defineStore("document", {
         this.documents.push(response.data);
         return response.data;
       } catch (error) {
-        this.error = error.response?.data?.error || "Failed to create document";
+        this.error = error.response?.data?.detail || "Failed to create document";
         throw error;
       } finally {
         this.loading = false;
@@ -122,18 +148,9 @@ export const useDocumentStore = defineStore("document", {
     async updateDocument(document) {
       this.loading = true;
       try {
-        // Format tags properly for the backend - ensure they are objects with a name property
         const formattedDocument = {
           ...document,
-          tags: document.tags.map((tag) => {
-            if (typeof tag === "object" && tag.name) {
-              return tag;
-            } else if (typeof tag === "string") {
-              return { name: tag };
-            } else {
-              return { name: String(tag) };
-            }
-          }),
+          tags: tagNames(document.tags),
           serviceId: document.serviceId
             ? parseInt(document.serviceId, 10)
             : null,
@@ -158,6 +175,8 @@ export const useDocumentStore = defineStore("document", {
               Authorization: `Bearer ${localStorage.getItem(
                 config.auth.tokenKey
               )}`,
+              // Reject the save if someone else changed the document meanwhile
+              "If-Match": `"${document.revision}"`,
             },
           }
         );
@@ -168,13 +187,57 @@ export const useDocumentStore = defineStore("document", {
         return response.data;
       } catch (error) {
         console.error("Error updating document:", error);
-        this.error = error.response?.data?.error || "Failed to update document";
+        if (error.response?.status === 409) {
+          this.error =
+            "This document was changed by someone else. Reload it and apply your edits again.";
+          throw error;
+        }
+        this.error = error.response?.data?.detail || "Failed to update document";
         throw error;
       } finally {
         this.loading = false;
       }
     },
 
+    async searchDocuments(query) {
+      try {
+        const response = await axios.get(`${config.api.baseUrl}/api/search`, {
+          params: { q: query, type: "document" },
+          headers: {
+            Authorization: `Bearer ${localStorage.getItem(
+              config.auth.tokenKey
+            )}`,
+          },
+        });
+        return response.data.results;
+      } catch (error) {
+        this.error = error.response?.data?.detail || "Failed to search documents";
+        throw error;
+      }
+    },
+
+    // Loads the categories documents can be filed under, in display order
+    async fetchCategories() {
+      try {
+        const response = await axios.get(
+          `${config.api.baseUrl}/api/categories`,
+          {
+            params: { limit: 100 },
+            headers: {
+              Authorization: `Bearer ${localStorage.getItem(
+                config.auth.tokenKey
+              )}`,
+            },
+          }
+        );
+        this.categories = response.data.items;
+      } catch (error) {
+        this.error =
+          error.response?.data?.detail || "Failed to fetch categories";
+        throw error;
+      }
+    },
+
     async deleteDocument(id) {
       this.loading = true;
       try {
@@ -187,7 +250,7 @@ export const useDocumentStore = defineStore("document", {
         });
         this.documents = this.documents.filter((d) => d.id !== id);
       } catch (error) {
-        this.error = error.response?.data?.error || "Failed to delete document";
+        this.error = error.response?.data?.detail || "Failed to delete document";
         throw error;
       } finally {
         this.loading = false;
@@ -4,10 +4,10 @@ import { config } from "../config";
 export const useServiceStore = defineStore("service", {
   state: () => ({
     services: [
-      { ID: "crmconnector", name: "Crm Connector" },
-      { ID: "asterisk", name: "Asterisk" },
-      { ID: "omnichannel", name: "Omnichannel" },
-      { ID: "clickhouse", name: "Clickhouse" },
+      { id: "crmconnector", name: "Crm Connector" },
+      { id: "asterisk", name: "Asterisk" },
+      { id: "omnichannel", name: "Omnichannel" },
+      { id: "clickhouse", name: "Clickhouse" },
     ],
     loading: false,
     error: null,